@@ -0,0 +1,76 @@
+package client
+
+import "context"
+
+// SetAsync sends a SET command without waiting for the response.
+//
+// The protocol is strictly request/response, so fire-and-forget writes use a
+// dedicated write-only connection, opened on first use, whose responses are
+// drained in the background. The tradeoff is that server errors are never
+// reported and writes are not ordered with respect to commands sent on the
+// main connection. Only network and encoding errors are returned.
+// Asynchronous writes bypass the interceptor chain.
+func (c *Client) SetAsync(key string, value interface{}) error {
+	cmd := SetCommand{
+		Set: SetData{
+			Key:   c.key(key),
+			Value: value,
+		},
+	}
+
+	if err := c.checkValue(cmd, value); err != nil {
+		return err
+	}
+
+	data, err := c.encodeCommand(cmd)
+	if err != nil {
+		return wrapOperationError(cmd, err)
+	}
+
+	c.asyncMu.Lock()
+	defer c.asyncMu.Unlock()
+
+	// Close marks the client closed before closing the async connection
+	// under asyncMu, so no connection is opened after it has run
+	if c.State() == Closed {
+		return wrapOperationError(cmd, ErrClientClosed)
+	}
+
+	if c.asyncConn == nil {
+		conn, err := c.dial(context.Background())
+		if err != nil {
+			return wrapOperationError(cmd, err)
+		}
+		c.asyncConn = c.derive(conn)
+		go c.asyncConn.discardResponses()
+	}
+
+	if err := c.asyncConn.writeFrame(data); err != nil {
+		// Drop the broken connection, the next call opens a new one
+		c.asyncConn.Close()
+		c.asyncConn = nil
+		return wrapOperationError(cmd, err)
+	}
+	return nil
+}
+
+// discardResponses reads and drops response frames until the connection fails
+func (c *Client) discardResponses() {
+	c.allowIdleReads()
+	for {
+		if _, err := c.readFrame(); err != nil {
+			return
+		}
+	}
+}
+
+// closeAsync closes the write-only connection used by SetAsync, if open
+func (c *Client) closeAsync() {
+	c.asyncMu.Lock()
+	defer c.asyncMu.Unlock()
+
+	if c.asyncConn != nil {
+		c.asyncConn.Close()
+		c.asyncConn = nil
+	}
+}
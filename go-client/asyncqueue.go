@@ -0,0 +1,216 @@
+package client
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned by AsyncClient.Set when the queue is full and
+// the client is not configured to block
+var ErrQueueFull = errors.New("async queue full")
+
+// ErrQueueClosed is returned when using an AsyncClient after Close
+var ErrQueueClosed = errors.New("async queue closed")
+
+// AsyncOption configures an AsyncClient
+type AsyncOption func(*AsyncClient)
+
+// WithBlockOnFull makes Set wait for room in the queue instead of
+// returning ErrQueueFull
+func WithBlockOnFull() AsyncOption {
+	return func(a *AsyncClient) {
+		a.block = true
+	}
+}
+
+// WithAsyncErrorHandler sets a function called with the key and the error
+// of every queued write that fails. Without a handler failures are dropped.
+func WithAsyncErrorHandler(handler func(key string, err error)) AsyncOption {
+	return func(a *AsyncClient) {
+		a.errorHandler = handler
+	}
+}
+
+// asyncItem is a queued write
+type asyncItem struct {
+	key   string
+	value interface{}
+}
+
+// AsyncClient queues writes in a bounded buffer and sends them from a
+// background flusher, batching whatever is queued into a single pipeline.
+// Set returns as soon as the write is queued, so server errors are only
+// reported through WithAsyncErrorHandler.
+type AsyncClient struct {
+	client       *Client
+	block        bool
+	errorHandler func(key string, err error)
+
+	queue chan asyncItem
+	done  chan struct{}
+
+	// closeMu guards the queue channel against sends after Close
+	closeMu sync.RWMutex
+	closed  bool
+
+	// pending counts the writes queued but not yet sent
+	pendingMu sync.Mutex
+	pending   int
+	drained   *sync.Cond
+}
+
+// Async starts a background flusher and returns an AsyncClient that queues
+// up to queueSize writes on the client
+func (c *Client) Async(queueSize int, opts ...AsyncOption) *AsyncClient {
+	a := &AsyncClient{
+		client: c,
+		queue:  make(chan asyncItem, queueSize),
+		done:   make(chan struct{}),
+	}
+	a.drained = sync.NewCond(&a.pendingMu)
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	go a.flushLoop()
+	return a
+}
+
+// Set queues a SET command. When the queue is full it returns ErrQueueFull,
+// or waits for room if WithBlockOnFull was given. The value is encoded when
+// queued, so later local mutations do not affect what is stored and
+// encoding errors are returned here.
+func (a *AsyncClient) Set(key string, value interface{}) error {
+	// Checked before the snapshot, which encodes nil to a non-nil null
+	cmd := SetCommand{Set: SetData{Key: a.client.key(key)}}
+	if err := a.client.checkValue(cmd, value); err != nil {
+		return err
+	}
+
+	snapshot, err := snapshotValue(value)
+	if err != nil {
+		return wrapOperationError(cmd, err)
+	}
+
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+
+	if a.closed {
+		return ErrQueueClosed
+	}
+
+	a.pendingMu.Lock()
+	a.pending++
+	a.pendingMu.Unlock()
+
+	item := asyncItem{key: key, value: snapshot}
+	if a.block {
+		a.queue <- item
+		return nil
+	}
+
+	select {
+	case a.queue <- item:
+		return nil
+	default:
+		a.finish(1)
+		return ErrQueueFull
+	}
+}
+
+// Flush waits until every write queued so far has been sent
+func (a *AsyncClient) Flush() {
+	a.pendingMu.Lock()
+	defer a.pendingMu.Unlock()
+
+	for a.pending > 0 {
+		a.drained.Wait()
+	}
+}
+
+// Close stops accepting writes and waits for the queued ones to be sent.
+// The underlying client is left open.
+func (a *AsyncClient) Close() error {
+	a.closeMu.Lock()
+	if a.closed {
+		a.closeMu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.closeMu.Unlock()
+
+	<-a.done
+	return nil
+}
+
+// flushLoop sends the queued writes until the queue is closed
+func (a *AsyncClient) flushLoop() {
+	defer close(a.done)
+
+	batch := make([]asyncItem, 0, cap(a.queue))
+	for item := range a.queue {
+		batch = append(batch[:0], item)
+
+		// Take whatever else is already queued
+	drain:
+		for len(batch) < cap(batch) {
+			select {
+			case item, ok := <-a.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, item)
+			default:
+				break drain
+			}
+		}
+
+		a.send(batch)
+		a.finish(len(batch))
+	}
+}
+
+// send writes a batch, pipelined unless the client is multiplexed
+func (a *AsyncClient) send(batch []asyncItem) {
+	if a.client.multiplexed {
+		for _, item := range batch {
+			a.report(item.key, a.client.Set(item.key, item.value))
+		}
+		return
+	}
+
+	p := a.client.Pipeline()
+	for _, item := range batch {
+		p.Set(item.key, item.value)
+	}
+
+	results, err := p.Exec()
+	if err != nil {
+		for _, item := range batch {
+			a.report(item.key, err)
+		}
+		return
+	}
+	for i, result := range results {
+		a.report(batch[i].key, result.Err)
+	}
+}
+
+// report passes a failed write to the error handler
+func (a *AsyncClient) report(key string, err error) {
+	if err != nil && a.errorHandler != nil {
+		a.errorHandler(key, err)
+	}
+}
+
+// finish marks n queued writes as sent
+func (a *AsyncClient) finish(n int) {
+	a.pendingMu.Lock()
+	a.pending -= n
+	if a.pending == 0 {
+		a.drained.Broadcast()
+	}
+	a.pendingMu.Unlock()
+}
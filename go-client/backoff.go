@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes the delay before a retry or reconnection attempt.
+// attempt starts at 1 for the first retry.
+type Backoff interface {
+	NextDelay(attempt int) time.Duration
+}
+
+// ConstantBackoff waits the same delay before every attempt
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// NextDelay implements Backoff
+func (b ConstantBackoff) NextDelay(attempt int) time.Duration {
+	return b.Delay
+}
+
+// ExponentialBackoff multiplies the delay by Multiplier after every attempt,
+// starting from Initial and capped at Max when Max is positive.
+// A Multiplier lower than 1 defaults to 2.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+// NextDelay implements Backoff
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		return b.Max
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// JitteredBackoff randomizes the delays of another Backoff, returning a value
+// between half and the whole of the wrapped delay, so that many clients
+// retrying at once do not stay synchronized
+type JitteredBackoff struct {
+	Backoff Backoff
+}
+
+// NextDelay implements Backoff
+func (b JitteredBackoff) NextDelay(attempt int) time.Duration {
+	delay := b.Backoff.NextDelay(attempt)
+	if delay <= 0 {
+		return delay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
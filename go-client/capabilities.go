@@ -0,0 +1,118 @@
+package client
+
+// HelloCommand represents a HELLO command
+type HelloCommand struct {
+	Hello interface{} `json:"Hello"`
+}
+
+// helloResult is the value returned by the HELLO handshake
+type helloResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+}
+
+// ServerCapabilities lists the optional features supported by the server
+type ServerCapabilities struct {
+	TTL           bool `json:"ttl"`
+	Subscriptions bool `json:"subscriptions"`
+	Transactions  bool `json:"transactions"`
+	Compression   bool `json:"compression"`
+	DryRun        bool `json:"dry_run"`
+}
+
+// Capability names, as reported by ErrUnsupportedCapability
+const (
+	CapabilityTTL           = "ttl"
+	CapabilitySubscriptions = "subscriptions"
+	CapabilityDryRun        = "dry_run"
+)
+
+// has reports whether the named capability is supported
+func (s *ServerCapabilities) has(capability string) bool {
+	switch capability {
+	case CapabilityTTL:
+		return s.TTL
+	case CapabilitySubscriptions:
+		return s.Subscriptions
+	case CapabilityDryRun:
+		return s.DryRun
+	default:
+		return true
+	}
+}
+
+// Capabilities performs the HELLO handshake, once, and returns the features
+// supported by the server. Once they are known, commands needing a missing
+// feature fail immediately with ErrUnsupportedCapability.
+func (c *Client) Capabilities() (*ServerCapabilities, error) {
+	c.mu.Lock()
+	known := c.capabilities
+	c.mu.Unlock()
+	if known != nil {
+		caps := *known
+		return &caps, nil
+	}
+
+	cmd := HelloCommand{
+		Hello: nil,
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var result helloResult
+	if err := decodeValue("", value, &result); err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+
+	c.mu.Lock()
+	c.capabilities = &result.Capabilities
+	c.mu.Unlock()
+
+	caps := result.Capabilities
+	return &caps, nil
+}
+
+// checkCapability returns ErrUnsupportedCapability if the server is known
+// not to support a feature the command needs. The caller must hold c.mu.
+func (c *Client) checkCapability(cmd interface{}) error {
+	if c.capabilities == nil {
+		return nil
+	}
+	if capability := requiredCapability(cmd); capability != "" && !c.capabilities.has(capability) {
+		return ErrUnsupportedCapability{Capability: capability}
+	}
+	return nil
+}
+
+// requireCapability returns ErrUnsupportedCapability unless Capabilities
+// reported the feature. It guards fields a server without the feature would
+// silently ignore, such as the dry-run flag, which would apply the write, or
+// a Set TTL, which would store the key without expiration. The caller must
+// not hold c.mu.
+func (c *Client) requireCapability(capability string) error {
+	c.mu.Lock()
+	caps := c.capabilities
+	c.mu.Unlock()
+
+	if caps == nil || !caps.has(capability) {
+		return ErrUnsupportedCapability{Capability: capability}
+	}
+	return nil
+}
+
+// requiredCapability returns the optional feature a command needs, if any
+func requiredCapability(cmd interface{}) string {
+	switch CommandTypeOf(cmd) {
+	case CommandSubscribe, CommandGetAndSubscribe, CommandSubscribeAll:
+		return CapabilitySubscriptions
+	case CommandMSetEx, CommandExpireAt, CommandSetExpireAt:
+		return CapabilityTTL
+	case CommandSet:
+		if set, ok := cmd.(SetCommand); ok && set.Set.TTLMs > 0 {
+			return CapabilityTTL
+		}
+	}
+	return ""
+}
@@ -2,10 +2,19 @@ package client
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -15,8 +24,12 @@ type SetCommand struct {
 }
 
 type SetData struct {
-	Key   string      `json:"key"`
-	Value interface{} `json:"value"`
+	Key            string          `json:"key"`
+	Value          interface{}     `json:"value"`
+	TTLMs          int64           `json:"ttl_ms,omitempty"`
+	Condition      string          `json:"condition,omitempty"`
+	ReturnPrevious bool            `json:"return_previous,omitempty"`
+	Durability     DurabilityLevel `json:"durability,omitempty"`
 }
 
 // GetCommand represents a GET command
@@ -64,232 +77,2077 @@ type MergeCommand struct {
 }
 
 type MergeData struct {
-	Key   string      `json:"key"`
-	Value interface{} `json:"value"`
+	Key      string        `json:"key"`
+	Value    interface{}   `json:"value"`
+	Strategy MergeStrategy `json:"strategy,omitempty"`
 }
 
+// MergeStrategy selects how nested objects are combined by a merge
+type MergeStrategy string
+
+const (
+	// ShallowMerge replaces top-level keys, nested objects included
+	ShallowMerge MergeStrategy = "shallow"
+	// DeepMerge merges nested objects recursively
+	DeepMerge MergeStrategy = "deep"
+)
+
 // PingCommand represents a PING command
 type PingCommand struct {
 	Ping interface{} `json:"Ping"`
 }
 
+// ScanRangeCommand represents a SCANRANGE command
+type ScanRangeCommand struct {
+	ScanRange ScanRangeData `json:"ScanRange"`
+}
+
+type ScanRangeData struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// QAppendUniqueCommand represents a QAPPENDUNIQUE command
+type QAppendUniqueCommand struct {
+	QAppendUnique QAppendUniqueData `json:"QAppendUnique"`
+}
+
+type QAppendUniqueData struct {
+	Key    string        `json:"key"`
+	Path   string        `json:"path"`
+	Values []interface{} `json:"values"`
+}
+
+// AddAutoKeyCommand represents an ADDAUTOKEY command
+type AddAutoKeyCommand struct {
+	AddAutoKey AddAutoKeyData `json:"AddAutoKey"`
+}
+
+type AddAutoKeyData struct {
+	Prefix string      `json:"prefix"`
+	Value  interface{} `json:"value"`
+}
+
+// MergeManyCommand represents a MERGEMANY command
+type MergeManyCommand struct {
+	MergeMany MergeManyData `json:"MergeMany"`
+}
+
+type MergeManyData struct {
+	Patches map[string]interface{} `json:"patches"`
+}
+
+// QGetMultiCommand represents a QGETMULTI command
+type QGetMultiCommand struct {
+	QGetMulti QGetMultiData `json:"QGetMulti"`
+}
+
+type QGetMultiData struct {
+	Key     string   `json:"key"`
+	Queries []string `json:"queries"`
+}
+
+// GetWithFoundCommand represents a GETWITHFOUND command
+type GetWithFoundCommand struct {
+	GetWithFound GetData `json:"GetWithFound"`
+}
+
+// QMergeCommand represents a QMERGE command
+type QMergeCommand struct {
+	QMerge QSetData `json:"QMerge"`
+}
+
+// MSetExCommand represents a MSETEX command
+type MSetExCommand struct {
+	MSetEx MSetExData `json:"MSetEx"`
+}
+
+type MSetExData struct {
+	Items []MSetExItem `json:"items"`
+}
+
+type MSetExItem struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	TTLMs int64       `json:"ttl_ms"`
+}
+
+// KeyValueTTL is a key/value pair with its own time to live
+type KeyValueTTL struct {
+	Key   string
+	Value interface{}
+	TTL   time.Duration
+}
+
+// QCountCommand represents a QCOUNT command
+type QCountCommand struct {
+	QCount QGetData `json:"QCount"`
+}
+
+// SetIfCommand represents a SETIF command
+type SetIfCommand struct {
+	SetIf SetIfData `json:"SetIf"`
+}
+
+type SetIfData struct {
+	Key       string      `json:"key"`
+	Condition string      `json:"condition"`
+	Value     interface{} `json:"value"`
+}
+
+// GetDeleteCommand represents a GETDELETE command
+type GetDeleteCommand struct {
+	GetDelete GetData `json:"GetDelete"`
+}
+
+// CommandsCommand represents a COMMANDS command
+type CommandsCommand struct {
+	Commands interface{} `json:"Commands"`
+}
+
+// SwapCommand represents a SWAP command
+type SwapCommand struct {
+	Swap SwapData `json:"Swap"`
+}
+
+type SwapData struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+// GetVersionedCommand represents a GETVERSIONED command
+type GetVersionedCommand struct {
+	GetVersioned GetData `json:"GetVersioned"`
+}
+
+// CompareAndSwapVersionCommand represents a COMPAREANDSWAPVERSION command
+type CompareAndSwapVersionCommand struct {
+	CompareAndSwapVersion CompareAndSwapVersionData `json:"CompareAndSwapVersion"`
+}
+
+type CompareAndSwapVersionData struct {
+	Key     string      `json:"key"`
+	Version uint64      `json:"version"`
+	Value   interface{} `json:"value"`
+}
+
+// QExplainCommand represents a QEXPLAIN command
+type QExplainCommand struct {
+	QExplain QGetData `json:"QExplain"`
+}
+
+// EvalCommand represents an EVAL command
+type EvalCommand struct {
+	Eval EvalData `json:"Eval"`
+}
+
+type EvalData struct {
+	Key  string                 `json:"key"`
+	Expr string                 `json:"expr"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// HashCommand represents a HASH command
+type HashCommand struct {
+	Hash GetData `json:"Hash"`
+}
+
+// SoftDeleteCommand represents a SOFTDELETE command
+type SoftDeleteCommand struct {
+	SoftDelete SoftDeleteData `json:"SoftDelete"`
+}
+
+type SoftDeleteData struct {
+	Key         string `json:"key"`
+	RetentionMs int64  `json:"retention_ms"`
+}
+
+// UndeleteCommand represents an UNDELETE command
+type UndeleteCommand struct {
+	Undelete GetData `json:"Undelete"`
+}
+
+// ExpireAtCommand represents an EXPIREAT command
+type ExpireAtCommand struct {
+	ExpireAt GetData `json:"ExpireAt"`
+}
+
+// SetExpireAtCommand represents a SETEXPIREAT command
+type SetExpireAtCommand struct {
+	SetExpireAt SetExpireAtData `json:"SetExpireAt"`
+}
+
+type SetExpireAtData struct {
+	Key  string `json:"key"`
+	AtMs int64  `json:"at_ms"`
+}
+
+// QAggregateCommand represents a QAGGREGATE command
+type QAggregateCommand struct {
+	QAggregate QAggregateData `json:"QAggregate"`
+}
+
+type QAggregateData struct {
+	Pattern string `json:"pattern"`
+	Query   string `json:"query"`
+	Func    string `json:"func"`
+}
+
+// QSliceCommand represents a QSLICE command
+type QSliceCommand struct {
+	QSlice QSliceData `json:"QSlice"`
+}
+
+type QSliceData struct {
+	Key   string `json:"key"`
+	Path  string `json:"path"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// DeleteByPatternCommand represents a DELETEBYPATTERN command
+type DeleteByPatternCommand struct {
+	DeleteByPattern DeleteByPatternData `json:"DeleteByPattern"`
+}
+
+type DeleteByPatternData struct {
+	Pattern string `json:"pattern"`
+	Limit   int    `json:"limit"`
+}
+
+// ScanCommand represents a SCAN command
+type ScanCommand struct {
+	Scan ScanData `json:"Scan"`
+}
+
+type ScanData struct {
+	Pattern string `json:"pattern"`
+}
+
+// QCasCommand represents a QCAS command
+type QCasCommand struct {
+	QCas QCasData `json:"QCas"`
+}
+
+type QCasData struct {
+	Key      string      `json:"key"`
+	Path     string      `json:"path"`
+	Expected interface{} `json:"expected"`
+	Value    interface{} `json:"value"`
+}
+
+// BRPopCommand represents a BRPOP command
+type BRPopCommand struct {
+	BRPop BRPopData `json:"BRPop"`
+}
+
+type BRPopData struct {
+	Key       string `json:"key"`
+	TimeoutMs int64  `json:"timeout_ms"`
+}
+
+// QGetBatchCommand represents a QGETBATCH command
+type QGetBatchCommand struct {
+	QGetBatch QGetBatchData `json:"QGetBatch"`
+}
+
+type QGetBatchData struct {
+	Items []QItem `json:"items"`
+}
+
+// QItem is a key and the JSONPath query to run on its value
+type QItem struct {
+	Key   string `json:"key"`
+	Query string `json:"query"`
+}
+
+// GetOrSetCommand represents a GETORSET command
+type GetOrSetCommand struct {
+	GetOrSet GetOrSetData `json:"GetOrSet"`
+}
+
+type GetOrSetData struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// CompactCommand represents a COMPACT command
+type CompactCommand struct {
+	Compact GetData `json:"Compact"`
+}
+
+// SetNameCommand represents a SETNAME command
+type SetNameCommand struct {
+	SetName SetNameData `json:"SetName"`
+}
+
+type SetNameData struct {
+	Name string `json:"name"`
+}
+
+// MergeCountCommand represents a MERGECOUNT command
+type MergeCountCommand struct {
+	MergeCount MergeData `json:"MergeCount"`
+}
+
 // Response represents a server response
 type Response struct {
-	Ok    interface{} `json:"Ok,omitempty"`
-	Error string      `json:"Error,omitempty"`
-	Pong  interface{} `json:"Pong,omitempty"`
+	Ok       interface{} `json:"Ok,omitempty"`
+	Error    string      `json:"Error,omitempty"`
+	Pong     interface{} `json:"Pong,omitempty"`
+	Warnings []string    `json:"Warnings,omitempty"`
 }
 
+// DefaultMaxResponseSize is the default upper bound for a response frame
+const DefaultMaxResponseSize = 512 << 20
+
+// readProgressChunk is the chunk size used when reporting read progress
+const readProgressChunk = 256 << 10
+
+// DefaultByteOrder is the byte order of the frame length prefix used by the server
+var DefaultByteOrder binary.ByteOrder = binary.BigEndian
+
 // Client represents a connection to the JSON database
 type Client struct {
-	conn   net.Conn
-	reader *bufio.Reader
-}
+	config
 
-// NewClient creates a new client connection to the specified address
-func NewClient(address string) (*Client, error) {
-	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
-	}
+	invoker Invoker
 
-	return &Client{
-		conn:   conn,
-		reader: bufio.NewReader(conn),
-	}, nil
-}
+	// mu serializes request/response exchanges on the connection
+	mu            sync.Mutex
+	conn          net.Conn
+	reader        *bufio.Reader
+	everConnected bool
+	demux         *demux
 
-// Close closes the connection to the server
-func (c *Client) Close() error {
-	if c.conn != nil {
-		return c.conn.Close()
-	}
-	return nil
+	state atomic.Int32
+
+	// supported holds the commands reported by the server, nil until known
+	supported map[string]bool
+	// capabilities holds the features reported by HELLO, nil until known
+	capabilities *ServerCapabilities
+	// anonymous is set once the server closed a connection on SetName
+	anonymous atomic.Bool
+
+	// liveMu guards live, a copy of conn readable while c.mu is held by a
+	// command in flight
+	liveMu sync.Mutex
+	live   net.Conn
+
+	asyncMu   sync.Mutex
+	asyncConn *Client
+
+	// types maps discriminators to the factories used by GetTyped
+	typesMu sync.RWMutex
+	types   map[string]func() interface{}
 }
 
-// sendCommand sends a command to the server and returns the response
-func (c *Client) sendCommand(cmd interface{}) (interface{}, error) {
-	// Serialize command to JSON
-	data, err := json.Marshal(cmd)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal command: %w", err)
-	}
+// config holds the settings applied by Options. It is shared by the
+// additional connections a client opens, e.g. for subscriptions.
+type config struct {
+	address     string
+	network     string
+	connFactory func(ctx context.Context) (net.Conn, error)
+	clientName  string
 
-	// Send length prefix (4 bytes, big endian)
-	length := uint32(len(data))
-	if err := binary.Write(c.conn, binary.BigEndian, length); err != nil {
-		return nil, fmt.Errorf("failed to write length: %w", err)
-	}
+	readBuffer  int
+	writeBuffer int
 
-	// Send JSON data
-	if _, err := c.conn.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to write data: %w", err)
-	}
+	byteOrder       binary.ByteOrder
+	maxResponseSize uint32
+	encoder         Encoder
 
-	// Read response length
-	var respLength uint32
-	if err := binary.Read(c.reader, binary.BigEndian, &respLength); err != nil {
-		return nil, fmt.Errorf("failed to read response length: %w", err)
-	}
+	interceptors []Interceptor
+	latency      *latencyTracker
 
-	// Read response data
-	respData := make([]byte, respLength)
-	if _, err := c.reader.Read(respData); err != nil {
-		return nil, fmt.Errorf("failed to read response data: %w", err)
-	}
+	rateLimiters   map[string]RateLimiter
+	commandClasses map[CommandType]string
 
-	// Parse response as generic interface first
-	var response interface{}
-	if err := json.Unmarshal(respData, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
+	warningHandler func(command string, warnings []string)
+	pushHandler    func(msg interface{})
+
+	queryCache *queryCache
+	typeField  string
+
+	keyNormalizer func(string) string
+	rejectNil     bool
+	dryRun        bool
+	keyPrefix     string
+	keyWidth      int
+
+	debugWriter  io.Writer
+	debugMu      *sync.Mutex
+	readProgress func(read, total int)
+	maxReadStall time.Duration
 
-	return response, nil
+	retry     *retryPolicy
+	reconnect *reconnectPolicy
+
+	stateListener func(old, new ConnState)
+
+	multiplexed bool
 }
 
-// parseResponse parses a generic response into specific types
-func parseResponse(resp interface{}) (value interface{}, err error) {
-	switch v := resp.(type) {
-	case string:
-		// Handle enum variants like "Pong"
-		if v == "Pong" {
-			return nil, nil // Pong successful
-		}
-		// Otherwise it's an error message or unknown
-		return nil, fmt.Errorf("unexpected string response: %s", v)
-	case map[string]interface{}:
-		// Handle structured responses like {"Ok": value} or {"Error": "message"}
-		if okValue, exists := v["Ok"]; exists {
-			return okValue, nil
-		}
-		if errorMsg, exists := v["Error"]; exists {
-			if errStr, ok := errorMsg.(string); ok {
-				return nil, fmt.Errorf("server error: %s", errStr)
-			}
-			return nil, fmt.Errorf("server error: %v", errorMsg)
-		}
-		return nil, fmt.Errorf("unknown response format: %v", v)
-	default:
-		return nil, fmt.Errorf("unexpected response type: %T", resp)
+// NewClient creates a new client connection to the specified address
+func NewClient(address string, opts ...Option) (*Client, error) {
+	c := newClient(address, opts)
+	if err := c.connect(context.Background()); err != nil {
+		return nil, err
 	}
+	return c, nil
 }
 
-// Set sets a value for the given key
-func (c *Client) Set(key string, value interface{}) error {
-	cmd := SetCommand{
-		Set: SetData{
-			Key:   key,
-			Value: value,
-		},
-	}
+// NewLazyClient creates a client that dials the specified address on its
+// first command rather than immediately. Concurrent first commands share a
+// single connection attempt.
+func NewLazyClient(address string, opts ...Option) *Client {
+	return newClient(address, opts)
+}
 
-	resp, err := c.sendCommand(cmd)
-	if err != nil {
-		return err
+// newClient creates an unconnected client with the given options applied
+func newClient(address string, opts []Option) *Client {
+	c := &Client{}
+	c.address = address
+	c.network = "tcp"
+	c.byteOrder = DefaultByteOrder
+	c.maxResponseSize = DefaultMaxResponseSize
+	c.encoder = ExternallyTagged{}
+	c.typeField = "type"
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	_, err = parseResponse(resp)
-	return err
+	c.invoker = chainInterceptors(c.interceptors, c.invokeWithRetry)
+	return c
 }
 
-// Get retrieves the value for the given key
-func (c *Client) Get(key string) (interface{}, error) {
-	cmd := GetCommand{
-		Get: GetData{
-			Key: key,
-		},
+// connect dials the server unless already connected.
+// The caller must hold c.mu or otherwise own the client exclusively.
+func (c *Client) connect(ctx context.Context) error {
+	// A closed client must not use its connection or dial again, e.g.
+	// after the multiplexed reader dropped the connection closed by Close
+	if c.State() == Closed {
+		return ErrClientClosed
 	}
 
-	resp, err := c.sendCommand(cmd)
-	if err != nil {
-		return nil, err
+	if c.conn != nil {
+		return nil
 	}
 
-	return parseResponse(resp)
-}
-
-// Delete removes the value for the given key
-func (c *Client) Delete(key string) error {
-	cmd := DeleteCommand{
-		Delete: DeleteData{
-			Key: key,
-		},
+	if c.everConnected {
+		c.setState(Reconnecting)
+	} else {
+		c.setState(Connecting)
 	}
 
-	resp, err := c.sendCommand(cmd)
+	conn, err := c.dial(ctx)
 	if err != nil {
+		c.setState(Disconnected)
 		return err
 	}
 
-	_, err = parseResponse(resp)
-	return err
+	c.setConn(conn)
+
+	// Close may have run during the dial: it either sees the new
+	// connection and closes it, or is seen here
+	if c.State() == Closed {
+		c.dropConn()
+		return ErrClientClosed
+	}
+
+	if c.multiplexed {
+		c.startDemux(conn)
+	}
+	c.everConnected = true
+	c.setState(Connected)
+	return nil
 }
 
-// QGet executes a JSONPath query on the value at the given key
-func (c *Client) QGet(key, query string) (interface{}, error) {
-	cmd := QGetCommand{
-		QGet: QGetData{
-			Key:   key,
-			Query: query,
-		},
+// dial opens a new connection, identified with the client name if one is set
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	conn, err := c.open(ctx)
+	if err != nil || c.clientName == "" || c.anonymous.Load() {
+		return conn, err
 	}
 
-	resp, err := c.sendCommand(cmd)
-	if err != nil {
+	err = c.sendName(ctx, conn)
+	if err == nil {
+		return conn, nil
+	}
+	conn.Close()
+	if !errors.Is(err, errNameRejected) {
 		return nil, err
 	}
 
-	return parseResponse(resp)
+	// The server closed the connection on the unknown command: connect
+	// again, and from now on, without a name
+	c.anonymous.Store(true)
+	return c.open(ctx)
 }
 
-// QSet sets a sub-property using JSONPath
-func (c *Client) QSet(key, path string, value interface{}) error {
-	cmd := QSetCommand{
-		QSet: QSetData{
-			Key:   key,
-			Path:  path,
-			Value: value,
-		},
+// open opens a new connection to the configured address, or through the
+// connection factory when one is set
+func (c *Client) open(ctx context.Context) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if c.connFactory != nil {
+		conn, err = c.connFactory(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+	} else {
+		dialer := net.Dialer{Timeout: 10 * time.Second}
+		conn, err = dialer.DialContext(ctx, c.network, c.address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", c.address, err)
+		}
 	}
 
-	resp, err := c.sendCommand(cmd)
-	if err != nil {
-		return err
+	if err := c.tuneConn(conn); err != nil {
+		conn.Close()
+		return nil, err
 	}
-
-	_, err = parseResponse(resp)
-	return err
+	return conn, nil
 }
 
-// Merge merges a JSON value with the existing value at the given key
-func (c *Client) Merge(key string, value interface{}) error {
-	cmd := MergeCommand{
-		Merge: MergeData{
-			Key:   key,
-			Value: value,
+// errNameRejected is returned by sendName when the server closed the
+// connection after receiving SetName
+var errNameRejected = errors.New("client name rejected")
+
+// sendName identifies a new connection with the configured client name.
+// It uses the plain request/response framing, also in multiplexed mode, as
+// nothing else is in flight yet. An "unknown command" error response is
+// ignored. Servers that cannot parse a command close the connection instead,
+// which is reported as errNameRejected.
+func (c *Client) sendName(ctx context.Context, conn net.Conn) error {
+	cmd := SetNameCommand{
+		SetName: SetNameData{
+			Name: c.clientName,
 		},
 	}
 
-	resp, err := c.sendCommand(cmd)
+	data, err := c.encodeCommand(cmd)
 	if err != nil {
 		return err
 	}
 
-	_, err = parseResponse(resp)
-	return err
-}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return fmt.Errorf("failed to set deadline: %w", err)
+		}
+		defer conn.SetDeadline(time.Time{})
+	}
 
-// Ping sends a ping to the server
-func (c *Client) Ping() error {
-	cmd := PingCommand{
-		Ping: nil,
+	resp, err := c.derive(conn).roundTrip(data)
+	if err == nil {
+		_, err = parseResponse(resp)
+		err = classifyServerError(cmd, err)
 	}
 
-	resp, err := c.sendCommand(cmd)
-	if err != nil {
-		return err
+	var unsupported ErrUnsupportedCommand
+	var terr *TransportError
+	switch {
+	case err == nil, errors.As(err, &unsupported):
+		return nil
+	case errors.As(err, &terr) && terr.WroteFully && connClosedByPeer(err):
+		return errNameRejected
+	default:
+		return fmt.Errorf("failed to set client name: %w", err)
 	}
+}
 
-	_, err = parseResponse(resp)
+// connClosedByPeer reports whether err means the server closed the connection
+func connClosedByPeer(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// tuneConn applies the socket buffer sizes to TCP connections
+func (c *Client) tuneConn(conn net.Conn) error {
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if c.readBuffer > 0 {
+		if err := tcp.SetReadBuffer(c.readBuffer); err != nil {
+			return fmt.Errorf("failed to set read buffer: %w", err)
+		}
+	}
+	if c.writeBuffer > 0 {
+		if err := tcp.SetWriteBuffer(c.writeBuffer); err != nil {
+			return fmt.Errorf("failed to set write buffer: %w", err)
+		}
+	}
+	return nil
+}
+
+// setConn attaches a connection to the client
+func (c *Client) setConn(conn net.Conn) {
+	if c.maxReadStall > 0 {
+		conn = &stallConn{Conn: conn, stall: c.maxReadStall}
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	c.setLive(conn)
+}
+
+// setLive records the current connection for Close, RemoteAddr and LocalAddr
+func (c *Client) setLive(conn net.Conn) {
+	c.liveMu.Lock()
+	c.live = conn
+	c.liveMu.Unlock()
+}
+
+// liveConn returns the current connection, or nil. Unlike c.conn it can be
+// read without waiting for a command in flight.
+func (c *Client) liveConn() net.Conn {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	return c.live
+}
+
+// derive returns a client with the same configuration bound to another connection
+func (c *Client) derive(conn net.Conn) *Client {
+	d := &Client{config: c.config}
+	// State changes of auxiliary connections are not reported
+	d.stateListener = nil
+	// Auxiliary connections use the plain request/response protocol
+	d.multiplexed = false
+	d.invoker = chainInterceptors(d.interceptors, d.invokeWithRetry)
+	d.setConn(conn)
+	return d
+}
+
+// Close closes the connection to the server. A command in flight is
+// interrupted rather than waited for.
+func (c *Client) Close() error {
+	c.setState(Closed)
+	c.closeAsync()
+	if conn := c.liveConn(); conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// ByteOrder returns the byte order used for frame length prefixes
+func (c *Client) ByteOrder() binary.ByteOrder {
+	return c.byteOrder
+}
+
+// RemoteAddr returns the address of the server the client is connected to
+func (c *Client) RemoteAddr() net.Addr {
+	conn := c.liveConn()
+	if conn == nil {
+		return nil
+	}
+	return conn.RemoteAddr()
+}
+
+// LocalAddr returns the local address of the client connection
+func (c *Client) LocalAddr() net.Addr {
+	conn := c.liveConn()
+	if conn == nil {
+		return nil
+	}
+	return conn.LocalAddr()
+}
+
+// sendCommand sends a command to the server and returns the response
+func (c *Client) sendCommand(cmd interface{}) (interface{}, error) {
+	return c.sendCommandContext(context.Background(), cmd)
+}
+
+// sendCommandContext sends a command through the interceptor chain
+func (c *Client) sendCommandContext(ctx context.Context, cmd interface{}) (interface{}, error) {
+	if c.State() == Closed {
+		return nil, ErrClientClosed
+	}
+	return c.invoker(ctx, cmd)
+}
+
+// call sends a command, parses the response and wraps any error with the
+// operation name and key
+func (c *Client) call(cmd interface{}) (interface{}, error) {
+	return c.callContext(context.Background(), cmd)
+}
+
+// callContext is like call with a context
+func (c *Client) callContext(ctx context.Context, cmd interface{}) (interface{}, error) {
+	resp, err := c.sendCommandContext(ctx, cmd)
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+
+	value, err := parseResponse(resp)
+	if err != nil {
+		return nil, wrapOperationError(cmd, classifyServerError(cmd, err))
+	}
+	return value, nil
+}
+
+// invoke is the terminal Invoker that writes the command to the wire
+func (c *Client) invoke(ctx context.Context, cmd interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := c.waitRateLimit(ctx, CommandTypeOf(cmd)); err != nil {
+		return nil, err
+	}
+
+	data, err := c.encodeCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.multiplexed {
+		return c.invokeMultiplexed(ctx, cmd, data)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkSupported(cmd); err != nil {
+		return nil, err
+	}
+	if err := c.checkCapability(cmd); err != nil {
+		return nil, err
+	}
+
+	if err := c.dialWithReconnect(ctx); err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("failed to set deadline: %w", err)
+		}
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	resp, err := c.roundTrip(data)
+	if err != nil {
+		// The connection is broken, or holds a late response, e.g. when a
+		// deadline interrupts the read: never reuse it. Dropping it also
+		// reports Disconnected, and the next command dials a new one.
+		c.dropConn()
+		return nil, err
+	}
+
+	c.handleWarnings(commandName(cmd), resp)
+
+	return resp, nil
+}
+
+// handleWarnings reports the warnings attached to a response, if any
+func (c *Client) handleWarnings(command string, resp interface{}) {
+	if c.warningHandler == nil {
+		return
+	}
+	warnings := responseWarnings(resp)
+	if len(warnings) > 0 {
+		c.warningHandler(command, warnings)
+	}
+}
+
+// responseWarnings extracts the "Warnings" field of a structured response
+func responseWarnings(resp interface{}) []string {
+	m, ok := resp.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	items, ok := m["Warnings"].([]interface{})
+	if !ok {
+		return nil
+	}
+	warnings := make([]string, 0, len(items))
+	for _, item := range items {
+		warnings = append(warnings, fmt.Sprint(item))
+	}
+	return warnings
+}
+
+// commandName returns the protocol name of a command, taken from the
+// JSON tag of its envelope field
+func commandName(cmd interface{}) string {
+	if prepared, ok := cmd.(PreparedCommand); ok {
+		return prepared.name
+	}
+
+	t := reflect.TypeOf(cmd)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.NumField() == 0 {
+		return ""
+	}
+
+	field := t.Field(0)
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// commandKey returns the key a command targets, if its payload has one
+func commandKey(cmd interface{}) string {
+	if prepared, ok := cmd.(PreparedCommand); ok {
+		return prepared.key
+	}
+
+	v := reflect.ValueOf(cmd)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.NumField() == 0 {
+		return ""
+	}
+
+	payload := v.Field(0)
+	if payload.Kind() != reflect.Struct {
+		return ""
+	}
+	key := payload.FieldByName("Key")
+	if !key.IsValid() || key.Kind() != reflect.String {
+		return ""
+	}
+	return key.String()
+}
+
+// checkSupported fails early for commands the server reported as unsupported.
+// The caller must hold c.mu.
+func (c *Client) checkSupported(cmd interface{}) error {
+	if c.supported == nil {
+		return nil
+	}
+	name := commandName(cmd)
+	if name == CommandCommands.String() || c.supported[name] {
+		return nil
+	}
+	return ErrUnsupportedCommand{Command: name}
+}
+
+// encodeCommand serializes a command to JSON with the configured encoder
+func (c *Client) encodeCommand(cmd interface{}) ([]byte, error) {
+	_, external := c.encoder.(ExternallyTagged)
+
+	if prepared, ok := cmd.(PreparedCommand); ok {
+		if external || prepared.err != nil {
+			return prepared.data, prepared.err
+		}
+		// Prepared with the default envelope: encode again
+		cmd = prepared.cmd
+	}
+
+	dryRun := c.dryRun && CommandTypeOf(cmd).write()
+	if dryRun {
+		if err := c.requireCapability(CapabilityDryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case dryRun:
+		data, err = c.encodeDryRun(cmd)
+	case external:
+		// The command structs already marshal to the default envelope
+		data, err = json.Marshal(cmd)
+	default:
+		data, err = c.encoder.Encode(commandName(cmd), commandPayload(cmd))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal command: %w", err)
+	}
+	if err := checkFrameSize(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// encodeDryRun encodes a write command with "dry_run": true added to its
+// payload, asking the server to validate it without applying it
+func (c *Client) encodeDryRun(cmd interface{}) ([]byte, error) {
+	body, err := json.Marshal(commandPayload(cmd))
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("payload %s is not an object", body)
+	}
+	if payload == nil {
+		payload = make(map[string]json.RawMessage, 1)
+	}
+	payload["dry_run"] = json.RawMessage("true")
+
+	return c.encoder.Encode(commandName(cmd), payload)
+}
+
+// checkFrameSize reports ErrValueTooLarge if data does not fit the 32-bit
+// length prefix, which would otherwise silently wrap and corrupt the stream
+func checkFrameSize(data []byte) error {
+	if uint64(len(data)) > math.MaxUint32 {
+		return fmt.Errorf("%w: %d bytes", ErrValueTooLarge, len(data))
+	}
+	return nil
+}
+
+// roundTrip sends an already serialized command and returns the response
+func (c *Client) roundTrip(data []byte) (interface{}, error) {
+	if err := c.writeFrame(data); err != nil {
+		return nil, &TransportError{WroteFully: false, Err: err}
+	}
+
+	resp, err := c.readResponse()
+	if err != nil {
+		return nil, &TransportError{WroteFully: true, Err: err}
+	}
+	return resp, nil
+}
+
+// readResponse reads the next response frame, routing out-of-band messages
+// to the push handler
+func (c *Client) readResponse() (interface{}, error) {
+	for {
+		respData, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		// Parse response as generic interface first
+		var response interface{}
+		if err := json.Unmarshal(respData, &response); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		// Out-of-band messages are not the reply to this command:
+		// hand them off and keep waiting for the actual response
+		if msg, ok := pushMessage(response); ok {
+			if c.pushHandler != nil {
+				c.pushHandler(msg)
+			}
+			continue
+		}
+
+		return response, nil
+	}
+}
+
+// pushMessage returns the payload of a server-initiated {"Push": ...} frame
+func pushMessage(resp interface{}) (interface{}, bool) {
+	m, ok := resp.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, false
+	}
+	msg, ok := m["Push"]
+	return msg, ok
+}
+
+// handlePush hands a {"Push": ...} frame to the push handler and reports
+// whether data was one
+func (c *Client) handlePush(data []byte) bool {
+	var resp interface{}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return false
+	}
+	msg, ok := pushMessage(resp)
+	if ok && c.pushHandler != nil {
+		c.pushHandler(msg)
+	}
+	return ok
+}
+
+// writeFrame writes a length-prefixed frame to the connection
+func (c *Client) writeFrame(data []byte) error {
+	if err := checkFrameSize(data); err != nil {
+		return err
+	}
+
+	c.debugFrame("-->", data)
+
+	// Send length prefix (4 bytes, in the configured byte order)
+	length := uint32(len(data))
+	if err := binary.Write(c.conn, c.byteOrder, length); err != nil {
+		return fmt.Errorf("failed to write length: %w", err)
+	}
+
+	// Send JSON data
+	if _, err := c.conn.Write(data); err != nil {
+		return fmt.Errorf("failed to write data: %w", err)
+	}
+
+	return nil
+}
+
+// readFrameLength reads the length prefix of the next response frame.
+// The caller must hold c.mu.
+func (c *Client) readFrameLength() (uint32, error) {
+	var respLength uint32
+	if err := binary.Read(c.reader, c.byteOrder, &respLength); err != nil {
+		return 0, fmt.Errorf("failed to read response length: %w", err)
+	}
+
+	// An absurd length usually means the peer is not speaking this protocol:
+	// fail fast instead of allocating and blocking on the read
+	if respLength > c.maxResponseSize {
+		c.dropConn()
+		return 0, fmt.Errorf("%w: response length %d exceeds maximum %d", ErrProtocolError, respLength, c.maxResponseSize)
+	}
+
+	return respLength, nil
+}
+
+// readFrame reads a whole length-prefixed response frame
+func (c *Client) readFrame() ([]byte, error) {
+	respLength, err := c.readFrameLength()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read response data
+	respData := make([]byte, respLength)
+	if c.readProgress != nil && respLength > readProgressChunk {
+		err = c.readChunked(respData)
+	} else {
+		_, err = io.ReadFull(c.reader, respData)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response data: %w", err)
+	}
+
+	c.debugFrame("<--", respData)
+
+	return respData, nil
+}
+
+// readChunked fills buf in chunks, reporting progress after each one
+func (c *Client) readChunked(buf []byte) error {
+	for read := 0; read < len(buf); {
+		end := read + readProgressChunk
+		if end > len(buf) {
+			end = len(buf)
+		}
+		n, err := io.ReadFull(c.reader, buf[read:end])
+		read += n
+		if err != nil {
+			return err
+		}
+		c.readProgress(read, len(buf))
+	}
+	return nil
+}
+
+// parseResponse parses a generic response into specific types
+func parseResponse(resp interface{}) (value interface{}, err error) {
+	switch v := resp.(type) {
+	case nil:
+		// A bare null is a successful response without a value
+		return nil, nil
+	case bool, float64:
+		// Bare scalars are returned as the value itself
+		return v, nil
+	case string:
+		// Handle enum variants like "Pong"
+		if v == "Pong" {
+			return nil, nil // Pong successful
+		}
+		// Otherwise it's an error message or unknown
+		return nil, fmt.Errorf("unexpected string response: %s", v)
+	case map[string]interface{}:
+		// Handle structured responses like {"Ok": value} or {"Error": "message"}
+		if okValue, exists := v["Ok"]; exists {
+			return okValue, nil
+		}
+		if pongValue, exists := v["Pong"]; exists {
+			// Pong carrying metadata, e.g. {"Pong": {...}}
+			return pongValue, nil
+		}
+		if serverBusy(v) {
+			return nil, ErrServerBusy
+		}
+		if errorMsg, exists := v["Error"]; exists {
+			if errStr, ok := errorMsg.(string); ok {
+				return nil, &ServerError{Message: errStr}
+			}
+			return nil, &ServerError{Message: fmt.Sprint(errorMsg)}
+		}
+		return nil, fmt.Errorf("unknown response format: %v", v)
+	default:
+		return nil, fmt.Errorf("unexpected response type: %T", resp)
+	}
+}
+
+// toStringSlice converts a decoded JSON array into a slice of strings
+func toStringSlice(value interface{}) ([]string, error) {
+	if value == nil {
+		return []string{}, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type: %T", value)
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		str, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected element type: %T", item)
+		}
+		result = append(result, str)
+	}
+	return result, nil
+}
+
+// toInt converts a decoded JSON number into an int
+func toInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("unexpected value type: %T", value)
+	}
+}
+
+// toSlice converts a decoded JSON array into a slice
+func toSlice(value interface{}) ([]interface{}, error) {
+	if value == nil {
+		return []interface{}{}, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type: %T", value)
+	}
+	return items, nil
+}
+
+// toFoundValue converts a {"found": bool, "value": any} result
+func toFoundValue(value interface{}) (interface{}, bool, error) {
+	result, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected value type: %T", value)
+	}
+	found, ok := result["found"].(bool)
+	if !ok {
+		return nil, false, fmt.Errorf("missing found flag in result: %v", result)
+	}
+	return result["value"], found, nil
+}
+
+// MarshalValue encodes a value exactly as it is sent to the server, so that
+// it can be validated, or its size checked, before calling Set
+func MarshalValue(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return data, nil
+}
+
+// checkValue rejects nil values for cmd when WithRejectNilValues is set
+func (c *Client) checkValue(cmd interface{}, value interface{}) error {
+	if c.rejectNil && isNil(value) {
+		return wrapOperationError(cmd, ErrNilValue)
+	}
+	return nil
+}
+
+// isNil reports whether value encodes to JSON null: a nil interface, or a
+// nil pointer, map, slice or interface
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// snapshotValue deep-copies a value by encoding it to JSON
+func snapshotValue(value interface{}) (json.RawMessage, error) {
+	data, err := MarshalValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(data), nil
+}
+
+// toBool converts a decoded JSON boolean
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("unexpected value type: %T", value)
+	}
+}
+
+// Set sets a value for the given key. Options add an expiration, a
+// condition on the key existence or ask for the previous value; when the
+// condition is not met ErrConditionNotMet is returned.
+func (c *Client) Set(key string, value interface{}, opts ...SetOption) error {
+	data := SetData{
+		Key:   c.key(key),
+		Value: value,
+	}
+	o := setOptions{data: &data}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cmd := SetCommand{Set: data}
+	if err := c.checkValue(cmd, value); err != nil {
+		return err
+	}
+	if data.TTLMs > 0 {
+		if err := c.requireCapability(CapabilityTTL); err != nil {
+			return wrapOperationError(cmd, err)
+		}
+	}
+
+	result, err := c.call(cmd)
+	if err != nil || len(opts) == 0 {
+		return err
+	}
+
+	return o.apply(cmd, result)
+}
+
+// SetCopy sets a value for the given key from a deep copy of value taken at
+// call time, so later local mutations cannot affect what is stored, even on
+// paths where encoding is deferred
+func (c *Client) SetCopy(key string, value interface{}, opts ...SetOption) error {
+	cmd := SetCommand{Set: SetData{Key: c.key(key)}}
+	if err := c.checkValue(cmd, value); err != nil {
+		return err
+	}
+
+	snapshot, err := snapshotValue(value)
+	if err != nil {
+		return wrapOperationError(cmd, err)
+	}
+	return c.Set(key, snapshot, opts...)
+}
+
+// Get retrieves the value for the given key
+func (c *Client) Get(key string) (interface{}, error) {
+	cmd := GetCommand{
+		Get: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	return c.call(cmd)
+}
+
+// GetInto retrieves the value for the given key and decodes it into dest
+func (c *Client) GetInto(key string, dest interface{}) error {
+	value, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+
+	return decodeValue(key, value, dest)
+}
+
+// Delete removes the value for the given key
+func (c *Client) Delete(key string) error {
+	cmd := DeleteCommand{
+		Delete: DeleteData{
+			Key: c.key(key),
+		},
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// QGet executes a JSONPath query on the value at the given key. The query
+// is either a string or a *Query. With WithQueryCache, results are served
+// from the cache until they expire.
+func (c *Client) QGet(key string, query interface{}) (interface{}, error) {
+	path, err := queryString(query)
+	cmd := QGetCommand{
+		QGet: QGetData{
+			Key:   c.key(key),
+			Query: path,
+		},
+	}
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+
+	if c.queryCache == nil {
+		return c.call(cmd)
+	}
+
+	if value, ok := c.queryCache.get(cmd.QGet.Key, path); ok {
+		return value, nil
+	}
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, err
+	}
+	c.queryCache.put(cmd.QGet.Key, path, value)
+	return value, nil
+}
+
+// QGetOr executes a JSONPath query and returns def when nothing matches
+func (c *Client) QGetOr(key, query string, def interface{}) (interface{}, error) {
+	value, err := c.QGet(key, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		return def, nil
+	}
+	if items, ok := value.([]interface{}); ok && len(items) == 0 {
+		return def, nil
+	}
+	return value, nil
+}
+
+// QSet sets a sub-property using JSONPath
+func (c *Client) QSet(key, path string, value interface{}) error {
+	cmd := QSetCommand{
+		QSet: QSetData{
+			Key:   c.key(key),
+			Path:  path,
+			Value: value,
+		},
+	}
+
+	if err := c.checkValue(cmd, value); err != nil {
+		return err
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// Merge merges a JSON value with the existing value at the given key
+func (c *Client) Merge(key string, value interface{}) error {
+	cmd := MergeCommand{
+		Merge: MergeData{
+			Key:   c.key(key),
+			Value: value,
+		},
+	}
+
+	if err := c.checkValue(cmd, value); err != nil {
+		return err
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// MergeWithStrategy merges a JSON value with the existing value at the given
+// key using an explicit merge strategy
+func (c *Client) MergeWithStrategy(key string, value interface{}, strategy MergeStrategy) error {
+	cmd := MergeCommand{
+		Merge: MergeData{
+			Key:      c.key(key),
+			Value:    value,
+			Strategy: strategy,
+		},
+	}
+
+	if err := c.checkValue(cmd, value); err != nil {
+		return err
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// Ping sends a ping to the server
+func (c *Client) Ping() error {
+	cmd := PingCommand{
+		Ping: nil,
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// ScanRange returns the keys lexicographically between start and end
+func (c *Client) ScanRange(start, end string) ([]string, error) {
+	cmd := ScanRangeCommand{
+		ScanRange: ScanRangeData{
+			Start: c.key(start),
+			End:   c.key(end),
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := toStringSlice(value)
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+	return c.unkeys(keys), nil
+}
+
+// QAppendUnique appends to the array at path the values not already present,
+// returning how many were added
+func (c *Client) QAppendUnique(key, path string, values ...interface{}) (int, error) {
+	cmd := QAppendUniqueCommand{
+		QAppendUnique: QAppendUniqueData{
+			Key:    c.key(key),
+			Path:   path,
+			Values: values,
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := toInt(value)
+	if err != nil {
+		return 0, wrapOperationError(cmd, err)
+	}
+	return n, nil
+}
+
+// AddAutoKey stores value under a server-assigned key of the form prefix:<n>,
+// where n is an atomically incremented counter, and returns the new key
+func (c *Client) AddAutoKey(prefix string, value interface{}) (string, error) {
+	cmd := AddAutoKeyCommand{
+		AddAutoKey: AddAutoKeyData{
+			Prefix: c.key(prefix),
+			Value:  value,
+		},
+	}
+
+	result, err := c.call(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := result.(string)
+	if !ok {
+		return "", wrapOperationError(cmd, fmt.Errorf("unexpected value type: %T", result))
+	}
+	return c.unkey(key), nil
+}
+
+// MergeMany merges a patch into each of the given keys in a single round trip
+func (c *Client) MergeMany(patches map[string]interface{}) error {
+	cmd := MergeManyCommand{
+		MergeMany: MergeManyData{
+			Patches: c.keyMap(patches),
+		},
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// QGetMulti executes several JSONPath queries on the value at the given key,
+// returning the results in the same order as the queries
+func (c *Client) QGetMulti(key string, queries []string) ([]interface{}, error) {
+	cmd := QGetMultiCommand{
+		QGetMulti: QGetMultiData{
+			Key:     c.key(key),
+			Queries: queries,
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := toSlice(value)
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+	if len(results) != len(queries) {
+		return nil, wrapOperationError(cmd, fmt.Errorf("expected %d results, got %d", len(queries), len(results)))
+	}
+	return results, nil
+}
+
+// GetWithFound retrieves the value for the given key, reporting whether the
+// key exists so that a stored null can be told apart from a missing key
+func (c *Client) GetWithFound(key string) (interface{}, bool, error) {
+	cmd := GetWithFoundCommand{
+		GetWithFound: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, found, err := toFoundValue(value)
+	if err != nil {
+		return nil, false, wrapOperationError(cmd, err)
+	}
+	return value, found, nil
+}
+
+// QMerge deep-merges a JSON value into the sub-object at the given path
+func (c *Client) QMerge(key, path string, value interface{}) error {
+	cmd := QMergeCommand{
+		QMerge: QSetData{
+			Key:   c.key(key),
+			Path:  path,
+			Value: value,
+		},
+	}
+	if err := c.checkValue(cmd, value); err != nil {
+		return err
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// SetManyEx sets multiple keys in a single round trip, each with its own TTL
+func (c *Client) SetManyEx(items []KeyValueTTL) error {
+	cmd := MSetExCommand{
+		MSetEx: MSetExData{
+			Items: make([]MSetExItem, 0, len(items)),
+		},
+	}
+	for _, item := range items {
+		if err := c.checkValue(cmd, item.Value); err != nil {
+			return err
+		}
+		cmd.MSetEx.Items = append(cmd.MSetEx.Items, MSetExItem{
+			Key:   c.key(item.Key),
+			Value: item.Value,
+			TTLMs: item.TTL.Milliseconds(),
+		})
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// QCount returns the number of nodes matched by a JSONPath query without
+// transferring them
+func (c *Client) QCount(key, query string) (int, error) {
+	cmd := QCountCommand{
+		QCount: QGetData{
+			Key:   c.key(key),
+			Query: query,
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := toInt(value)
+	if err != nil {
+		return 0, wrapOperationError(cmd, err)
+	}
+	return n, nil
+}
+
+// SetIf sets the value for the given key only if condQuery, evaluated by the
+// server on the current value, yields a truthy result. It reports whether
+// the value was set.
+func (c *Client) SetIf(key, condQuery string, value interface{}) (bool, error) {
+	cmd := SetIfCommand{
+		SetIf: SetIfData{
+			Key:       c.key(key),
+			Condition: condQuery,
+			Value:     value,
+		},
+	}
+
+	result, err := c.call(cmd)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := toBool(result)
+	if err != nil {
+		return false, wrapOperationError(cmd, err)
+	}
+	return ok, nil
+}
+
+// GetDelete atomically retrieves and removes the value for the given key,
+// reporting whether the key existed
+func (c *Client) GetDelete(key string) (interface{}, bool, error) {
+	cmd := GetDeleteCommand{
+		GetDelete: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, found, err := toFoundValue(value)
+	if err != nil {
+		return nil, false, wrapOperationError(cmd, err)
+	}
+	return value, found, nil
+}
+
+// Commands returns the names of the commands supported by the server.
+// The list is remembered: afterwards, commands the server does not support
+// fail immediately with ErrUnsupportedCommand instead of after a round trip.
+func (c *Client) Commands() ([]string, error) {
+	cmd := CommandsCommand{
+		Commands: nil,
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := toStringSlice(value)
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+
+	supported := make(map[string]bool, len(names))
+	for _, name := range names {
+		supported[name] = true
+	}
+	c.mu.Lock()
+	c.supported = supported
+	c.mu.Unlock()
+
+	return names, nil
+}
+
+// SwapKeys atomically exchanges the values stored at two keys
+func (c *Client) SwapKeys(a, b string) error {
+	cmd := SwapCommand{
+		Swap: SwapData{
+			A: c.key(a),
+			B: c.key(b),
+		},
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// GetVersioned retrieves the value for the given key together with the
+// revision the server tracks for it, for use with CompareAndSwapVersion
+func (c *Client) GetVersioned(key string) (interface{}, uint64, bool, error) {
+	cmd := GetVersionedCommand{
+		GetVersioned: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	result, err := c.call(cmd)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	value, found, err := toFoundValue(result)
+	if err != nil {
+		return nil, 0, false, wrapOperationError(cmd, err)
+	}
+	if !found {
+		return nil, 0, false, nil
+	}
+
+	version, ok := result.(map[string]interface{})["version"].(float64)
+	if !ok {
+		return nil, 0, false, wrapOperationError(cmd, fmt.Errorf("missing version in result: %v", result))
+	}
+
+	return value, uint64(version), true, nil
+}
+
+// CompareAndSwapVersion sets the value for the given key only if its
+// revision still equals version. It reports whether the value was set.
+func (c *Client) CompareAndSwapVersion(key string, version uint64, value interface{}) (bool, error) {
+	cmd := CompareAndSwapVersionCommand{
+		CompareAndSwapVersion: CompareAndSwapVersionData{
+			Key:     c.key(key),
+			Version: version,
+			Value:   value,
+		},
+	}
+
+	result, err := c.call(cmd)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := toBool(result)
+	if err != nil {
+		return false, wrapOperationError(cmd, err)
+	}
+	return ok, nil
+}
+
+// QExplain returns the server's human-readable evaluation plan and cost
+// estimate for a JSONPath query, without running it
+func (c *Client) QExplain(key, query string) (string, error) {
+	cmd := QExplainCommand{
+		QExplain: QGetData{
+			Key:   c.key(key),
+			Query: query,
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	plan, ok := value.(string)
+	if !ok {
+		return "", wrapOperationError(cmd, fmt.Errorf("unexpected value type: %T", value))
+	}
+	return plan, nil
+}
+
+// Eval atomically evaluates a server-side expression against the value at
+// the given key, with optional named arguments, and returns its result
+func (c *Client) Eval(key, expr string, args map[string]interface{}) (interface{}, error) {
+	cmd := EvalCommand{
+		Eval: EvalData{
+			Key:  c.key(key),
+			Expr: expr,
+			Args: args,
+		},
+	}
+
+	return c.call(cmd)
+}
+
+// ValueHash returns a stable content hash of the value at the given key,
+// computed by the server over its canonical JSON, to detect changes without
+// fetching the value
+func (c *Client) ValueHash(key string) (string, error) {
+	cmd := HashCommand{
+		Hash: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	hash, ok := value.(string)
+	if !ok {
+		return "", wrapOperationError(cmd, fmt.Errorf("unexpected value type: %T", value))
+	}
+	return hash, nil
+}
+
+// SoftDelete hides the given key, retaining its value for the retention
+// window so that it can be restored with Undelete. After the window the
+// server removes the key permanently.
+func (c *Client) SoftDelete(key string, retention time.Duration) error {
+	cmd := SoftDeleteCommand{
+		SoftDelete: SoftDeleteData{
+			Key:         c.key(key),
+			RetentionMs: retention.Milliseconds(),
+		},
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// Undelete restores a key removed by SoftDelete within its retention window.
+// It reports whether the key was restored.
+func (c *Client) Undelete(key string) (bool, error) {
+	cmd := UndeleteCommand{
+		Undelete: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := toBool(value)
+	if err != nil {
+		return false, wrapOperationError(cmd, err)
+	}
+	return ok, nil
+}
+
+// ExpireAt returns the absolute expiration time of the given key, as
+// computed by the server. It returns the zero time if the key has no
+// expiration.
+func (c *Client) ExpireAt(key string) (time.Time, error) {
+	cmd := ExpireAtCommand{
+		ExpireAt: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := parseTimestamp(value)
+	if err != nil {
+		return time.Time{}, wrapOperationError(cmd, err)
+	}
+	return t, nil
+}
+
+// SetExpireAt makes the given key expire at the absolute time t
+func (c *Client) SetExpireAt(key string, t time.Time) error {
+	cmd := SetExpireAtCommand{
+		SetExpireAt: SetExpireAtData{
+			Key:  c.key(key),
+			AtMs: t.UnixMilli(),
+		},
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
+
+// QAggregate applies a JSONPath query to every key matching the glob pattern
+// and aggregates the matched values server-side with aggFunc, one of "sum",
+// "avg", "min", "max" or "count"
+func (c *Client) QAggregate(pattern, query, aggFunc string) (interface{}, error) {
+	cmd := QAggregateCommand{
+		QAggregate: QAggregateData{
+			Pattern: c.key(pattern),
+			Query:   query,
+			Func:    aggFunc,
+		},
+	}
+
+	return c.call(cmd)
+}
+
+// QSlice returns the elements in [start, end) of the array at path, without
+// transferring the rest of the array
+func (c *Client) QSlice(key, path string, start, end int) ([]interface{}, error) {
+	cmd := QSliceCommand{
+		QSlice: QSliceData{
+			Key:   c.key(key),
+			Path:  path,
+			Start: start,
+			End:   end,
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := toSlice(value)
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+	return items, nil
+}
+
+// DeleteByPattern deletes up to limit keys matching the glob pattern and
+// returns how many were deleted. Call it again while it returns limit to
+// delete every match in bounded batches. limit must be at least 1.
+func (c *Client) DeleteByPattern(pattern string, limit int) (int, error) {
+	cmd := DeleteByPatternCommand{
+		DeleteByPattern: DeleteByPatternData{
+			Pattern: c.key(pattern),
+			Limit:   limit,
+		},
+	}
+	if limit < 1 {
+		return 0, wrapOperationError(cmd, fmt.Errorf("invalid limit: %d", limit))
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := toInt(value)
+	if err != nil {
+		return 0, wrapOperationError(cmd, err)
+	}
+	return n, nil
+}
+
+// QCompareAndSet sets the value at path to newValue only if its current
+// value equals expected, reporting whether the swap happened
+func (c *Client) QCompareAndSet(key, path string, expected, newValue interface{}) (bool, error) {
+	cmd := QCasCommand{
+		QCas: QCasData{
+			Key:      c.key(key),
+			Path:     path,
+			Expected: expected,
+			Value:    newValue,
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := toBool(value)
+	if err != nil {
+		return false, wrapOperationError(cmd, err)
+	}
+	return ok, nil
+}
+
+// BRPop removes and returns the last element of the array at the given key,
+// waiting server-side up to timeout for one to be pushed. found is false if
+// the timeout elapsed.
+//
+// The connection is busy while waiting, so other commands of a
+// non-multiplexed client wait too: workers should use a dedicated client.
+// The wait also counts as a stall for WithMaxReadStall. If ctx ends before
+// the server answers, the connection is dropped so that a late reply cannot
+// be read by the next command; an element popped by that reply is lost, so
+// ctx should outlast timeout.
+func (c *Client) BRPop(ctx context.Context, key string, timeout time.Duration) (interface{}, bool, error) {
+	cmd := BRPopCommand{
+		BRPop: BRPopData{
+			Key:       c.key(key),
+			TimeoutMs: timeout.Milliseconds(),
+		},
+	}
+
+	value, err := c.callContext(ctx, cmd)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, found, err := toFoundValue(value)
+	if err != nil {
+		return nil, false, wrapOperationError(cmd, err)
+	}
+	return value, found, nil
+}
+
+// QGetBatch runs a JSONPath query per key in a single request, returning the
+// results in the same order as the items
+func (c *Client) QGetBatch(items []QItem) ([]interface{}, error) {
+	keyed := make([]QItem, len(items))
+	for i, item := range items {
+		keyed[i] = QItem{Key: c.key(item.Key), Query: item.Query}
+	}
+	cmd := QGetBatchCommand{
+		QGetBatch: QGetBatchData{
+			Items: keyed,
+		},
+	}
+
+	value, err := c.call(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := toSlice(value)
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+	if len(results) != len(items) {
+		return nil, wrapOperationError(cmd, fmt.Errorf("expected %d results, got %d", len(items), len(results)))
+	}
+	return results, nil
+}
+
+// GetOrSet atomically returns the value for the given key, storing
+// defaultValue first if the key is absent. created reports whether this call
+// stored it, so exactly one of several concurrent callers sees true.
+func (c *Client) GetOrSet(key string, defaultValue interface{}) (value interface{}, created bool, err error) {
+	cmd := GetOrSetCommand{
+		GetOrSet: GetOrSetData{
+			Key:   c.key(key),
+			Value: defaultValue,
+		},
+	}
+	if err := c.checkValue(cmd, defaultValue); err != nil {
+		return nil, false, err
+	}
+
+	result, err := c.call(cmd)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, false, wrapOperationError(cmd, fmt.Errorf("unexpected value type: %T", result))
+	}
+	created, ok = m["created"].(bool)
+	if !ok {
+		return nil, false, wrapOperationError(cmd, fmt.Errorf("missing created in result: %v", result))
+	}
+	return m["value"], created, nil
+}
+
+// Compact asks the server to rewrite the value at the given key in its
+// optimal form, reclaiming the space left by many in-place updates
+func (c *Client) Compact(key string) error {
+	cmd := CompactCommand{
+		Compact: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	_, err := c.call(cmd)
 	return err
 }
+
+// MergeCount merges a JSON value with the existing value at the given key
+// like Merge, and returns the number of fields added or modified. Zero means
+// the merge left the value unchanged.
+func (c *Client) MergeCount(key string, value interface{}) (int, error) {
+	cmd := MergeCountCommand{
+		MergeCount: MergeData{
+			Key:   c.key(key),
+			Value: value,
+		},
+	}
+
+	if err := c.checkValue(cmd, value); err != nil {
+		return 0, err
+	}
+
+	result, err := c.call(cmd)
+	if err != nil {
+		return 0, err
+	}
+	n, err := toInt(result)
+	if err != nil {
+		return 0, wrapOperationError(cmd, err)
+	}
+	return n, nil
+}
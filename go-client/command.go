@@ -0,0 +1,211 @@
+package client
+
+import "fmt"
+
+// CommandType identifies a protocol command
+type CommandType int
+
+const (
+	CommandUnknown CommandType = iota
+	CommandSet
+	CommandGet
+	CommandDelete
+	CommandQGet
+	CommandQSet
+	CommandMerge
+	CommandPing
+	CommandScanRange
+	CommandQAppendUnique
+	CommandAddAutoKey
+	CommandMergeMany
+	CommandQGetMulti
+	CommandGetWithFound
+	CommandQMerge
+	CommandMSetEx
+	CommandGetAndSubscribe
+	CommandQCount
+	CommandSetIf
+	CommandGetDelete
+	CommandCommands
+	CommandSubscribe
+	CommandSwap
+	CommandGetVersioned
+	CommandCompareAndSwapVersion
+	CommandQExplain
+	CommandEval
+	CommandHash
+	CommandSoftDelete
+	CommandUndelete
+	CommandSubscribeAll
+	CommandExpireAt
+	CommandSetExpireAt
+	CommandQAggregate
+	CommandQSlice
+	CommandDeleteByPattern
+	CommandHello
+	CommandScan
+	CommandQCas
+	CommandBRPop
+	CommandSnapshot
+	CommandSnapshotExport
+	CommandQGetBatch
+	CommandGetOrSet
+	CommandMSet
+	CommandCompact
+	CommandSetName
+	CommandMergeCount
+)
+
+// commandTypeNames maps command types to their protocol names
+var commandTypeNames = [...]string{
+	CommandUnknown:               "Unknown",
+	CommandSet:                   "Set",
+	CommandGet:                   "Get",
+	CommandDelete:                "Delete",
+	CommandQGet:                  "QGet",
+	CommandQSet:                  "QSet",
+	CommandMerge:                 "Merge",
+	CommandPing:                  "Ping",
+	CommandScanRange:             "ScanRange",
+	CommandQAppendUnique:         "QAppendUnique",
+	CommandAddAutoKey:            "AddAutoKey",
+	CommandMergeMany:             "MergeMany",
+	CommandQGetMulti:             "QGetMulti",
+	CommandGetWithFound:          "GetWithFound",
+	CommandQMerge:                "QMerge",
+	CommandMSetEx:                "MSetEx",
+	CommandGetAndSubscribe:       "GetAndSubscribe",
+	CommandQCount:                "QCount",
+	CommandSetIf:                 "SetIf",
+	CommandGetDelete:             "GetDelete",
+	CommandCommands:              "Commands",
+	CommandSubscribe:             "Subscribe",
+	CommandSwap:                  "Swap",
+	CommandGetVersioned:          "GetVersioned",
+	CommandCompareAndSwapVersion: "CompareAndSwapVersion",
+	CommandQExplain:              "QExplain",
+	CommandEval:                  "Eval",
+	CommandHash:                  "Hash",
+	CommandSoftDelete:            "SoftDelete",
+	CommandUndelete:              "Undelete",
+	CommandSubscribeAll:          "SubscribeAll",
+	CommandExpireAt:              "ExpireAt",
+	CommandSetExpireAt:           "SetExpireAt",
+	CommandQAggregate:            "QAggregate",
+	CommandQSlice:                "QSlice",
+	CommandDeleteByPattern:       "DeleteByPattern",
+	CommandHello:                 "Hello",
+	CommandScan:                  "Scan",
+	CommandQCas:                  "QCas",
+	CommandBRPop:                 "BRPop",
+	CommandSnapshot:              "Snapshot",
+	CommandSnapshotExport:        "SnapshotExport",
+	CommandQGetBatch:             "QGetBatch",
+	CommandGetOrSet:              "GetOrSet",
+	CommandMSet:                  "MSet",
+	CommandCompact:               "Compact",
+	CommandSetName:               "SetName",
+	CommandMergeCount:            "MergeCount",
+}
+
+// commandTypesByName is the reverse lookup of commandTypeNames
+var commandTypesByName = func() map[string]CommandType {
+	m := make(map[string]CommandType, len(commandTypeNames))
+	for t, name := range commandTypeNames {
+		m[name] = CommandType(t)
+	}
+	return m
+}()
+
+// String returns the protocol name of the command
+func (t CommandType) String() string {
+	if t >= 0 && int(t) < len(commandTypeNames) {
+		return commandTypeNames[t]
+	}
+	return fmt.Sprintf("CommandType(%d)", int(t))
+}
+
+// CommandTypeOf returns the type of a command value, as received by an
+// Interceptor, or CommandUnknown
+func CommandTypeOf(cmd interface{}) CommandType {
+	if t, ok := commandTypesByName[commandName(cmd)]; ok {
+		return t
+	}
+	return CommandUnknown
+}
+
+// idempotentCommands lists the commands that can be applied twice with the
+// same outcome, and are therefore safe to retry
+var idempotentCommands = map[CommandType]bool{
+	CommandGet:          true,
+	CommandQGet:         true,
+	CommandPing:         true,
+	CommandScanRange:    true,
+	CommandQGetMulti:    true,
+	CommandGetWithFound: true,
+	CommandQCount:       true,
+	CommandCommands:     true,
+	CommandSet:          true,
+	CommandDelete:       true,
+	CommandQSet:         true,
+	CommandGetVersioned: true,
+	CommandQExplain:     true,
+	CommandHash:         true,
+	CommandExpireAt:     true,
+	CommandQAggregate:   true,
+	CommandQSlice:       true,
+	CommandScan:         true,
+	CommandSetExpireAt:  true,
+	CommandQGetBatch:    true,
+	CommandMSet:         true,
+	CommandCompact:      true,
+}
+
+// idempotent reports whether the command is safe to retry
+func (t CommandType) idempotent() bool {
+	return idempotentCommands[t]
+}
+
+// idempotent reports whether a command value is safe to retry. A Set with a
+// condition or ReturnPrevious is not: replayed after a lost response, it
+// would fail its condition or return its own value as the previous one.
+func idempotent(cmd interface{}) bool {
+	if set, ok := cmd.(SetCommand); ok && (set.Set.Condition != "" || set.Set.ReturnPrevious) {
+		return false
+	}
+	return CommandTypeOf(cmd).idempotent()
+}
+
+// writeCommands lists the commands that may modify stored data, which
+// WithDryRun marks as dry runs
+var writeCommands = map[CommandType]bool{
+	CommandSet:                   true,
+	CommandDelete:                true,
+	CommandQSet:                  true,
+	CommandMerge:                 true,
+	CommandQAppendUnique:         true,
+	CommandAddAutoKey:            true,
+	CommandMergeMany:             true,
+	CommandQMerge:                true,
+	CommandMSetEx:                true,
+	CommandSetIf:                 true,
+	CommandGetDelete:             true,
+	CommandSwap:                  true,
+	CommandCompareAndSwapVersion: true,
+	CommandEval:                  true,
+	CommandSoftDelete:            true,
+	CommandUndelete:              true,
+	CommandSetExpireAt:           true,
+	CommandDeleteByPattern:       true,
+	CommandQCas:                  true,
+	CommandBRPop:                 true,
+	CommandGetOrSet:              true,
+	CommandMSet:                  true,
+	CommandCompact:               true,
+	CommandMergeCount:            true,
+}
+
+// write reports whether the command may modify stored data
+func (t CommandType) write() bool {
+	return writeCommands[t]
+}
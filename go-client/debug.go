@@ -0,0 +1,26 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// debugFrame dumps a raw frame as indented JSON to the debug writer, if set
+func (c *Client) debugFrame(direction string, data []byte) {
+	if c.debugWriter == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		// Not valid JSON: dump it verbatim
+		buf.Reset()
+		buf.Write(data)
+	}
+	// The demultiplexer, the SetAsync drain and subscriptions write
+	// concurrently; derived clients share the mutex through the config
+	c.debugMu.Lock()
+	defer c.debugMu.Unlock()
+	fmt.Fprintf(c.debugWriter, "%s %s\n", direction, buf.Bytes())
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Encoder builds the wire envelope of a command from its protocol name
+// (e.g. "Set") and payload. Swapping the encoder adapts the client to a
+// server using a different serialization of the command enum.
+type Encoder interface {
+	Encode(name string, payload interface{}) ([]byte, error)
+}
+
+// ExternallyTagged is the default encoder. It wraps the payload in an
+// object keyed by the command name: {"Set": {"key": ..., "value": ...}}.
+type ExternallyTagged struct{}
+
+// Encode implements Encoder
+func (ExternallyTagged) Encode(name string, payload interface{}) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{name: payload})
+}
+
+// InternallyTagged stores the command name in a field of the payload
+// object: {"type": "Set", "key": ..., "value": ...}. Tag is the name of
+// that field, "type" if empty.
+type InternallyTagged struct {
+	Tag string
+}
+
+// Encode implements Encoder
+func (e InternallyTagged) Encode(name string, payload interface{}) ([]byte, error) {
+	tag := e.Tag
+	if tag == "" {
+		tag = "type"
+	}
+
+	head, err := json.Marshal(map[string]string{tag: name})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.Equal(body, []byte("null")), bytes.Equal(body, []byte("{}")):
+		return head, nil
+	case body[0] == '{':
+		// Splice the tag into the payload object
+		data := append(head[:len(head)-1], ',')
+		return append(data, body[1:]...), nil
+	default:
+		return nil, fmt.Errorf("command %s: payload %s is not an object", name, body)
+	}
+}
+
+// commandPayload returns the payload of a command struct, its first field
+func commandPayload(cmd interface{}) interface{} {
+	v := reflect.ValueOf(cmd)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.NumField() == 0 {
+		return nil
+	}
+	return v.Field(0).Interface()
+}
@@ -0,0 +1,46 @@
+package client
+
+import "testing"
+
+func TestInternallyTaggedEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoder InternallyTagged
+		command string
+		payload interface{}
+		want    string
+	}{
+		{"splice", InternallyTagged{}, "Set", SetData{Key: "a", Value: 1}, `{"type":"Set","key":"a","value":1}`},
+		{"custom tag", InternallyTagged{Tag: "cmd"}, "Get", GetData{Key: "a"}, `{"cmd":"Get","key":"a"}`},
+		{"nil payload", InternallyTagged{}, "Ping", nil, `{"type":"Ping"}`},
+		{"empty payload", InternallyTagged{}, "Snapshot", struct{}{}, `{"type":"Snapshot"}`},
+		{"map payload", InternallyTagged{}, "Set", map[string]interface{}{"key": "a"}, `{"type":"Set","key":"a"}`},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.encoder.Encode(tt.command, tt.payload)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInternallyTaggedEncodeNonObject(t *testing.T) {
+	if _, err := (InternallyTagged{}).Encode("Set", []int{1}); err == nil {
+		t.Fatal("expected an error for an array payload")
+	}
+}
+
+func TestExternallyTaggedEncode(t *testing.T) {
+	got, err := ExternallyTagged{}.Encode("Get", GetData{Key: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Get":{"key":"a"}}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
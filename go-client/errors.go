@@ -0,0 +1,166 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrProtocolError is returned when the server sends data that does not
+// follow the protocol framing. The connection is closed when it occurs.
+var ErrProtocolError = errors.New("protocol error")
+
+// ErrValueTooLarge is returned when an encoded command does not fit in a
+// frame, whose length prefix is 32 bits
+var ErrValueTooLarge = errors.New("value too large")
+
+// ErrNilValue is returned by writes of a nil value when WithRejectNilValues
+// is set
+var ErrNilValue = errors.New("nil value")
+
+// ErrUnknownType is reported by GetTyped for a discriminator with no
+// registered type
+var ErrUnknownType = errors.New("unknown type")
+
+// ErrConditionNotMet is returned when a conditional write was not applied
+// because its condition did not hold
+var ErrConditionNotMet = errors.New("condition not met")
+
+// ErrClientClosed is returned by commands issued after Close
+var ErrClientClosed = errors.New("client closed")
+
+// ErrServerBusy is returned when the server rejected a command because it
+// is under pressure, e.g. short on memory, and asks clients to slow down.
+// The command was not applied.
+var ErrServerBusy = errors.New("server busy")
+
+// ErrMultiplexed is returned by operations that need exclusive use of the
+// connection, such as pipelines and streams, on a multiplexed client
+var ErrMultiplexed = errors.New("operation not supported in multiplexed mode")
+
+// DecodeError reports a failure to decode a stored value into a Go type.
+// Type is nil when the failure happened before a type was chosen, e.g. for
+// a GetTyped value without a known discriminator.
+type DecodeError struct {
+	Key  string
+	Type reflect.Type
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Type == nil {
+		return fmt.Sprintf("decode key %q: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("decode key %q into %v: %v", e.Key, e.Type, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// OperationError records the operation and key of a failed command
+type OperationError struct {
+	Op  string
+	Key string
+	Err error
+}
+
+func (e *OperationError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("operation %q: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("operation %q key %q: %v", e.Op, e.Key, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// wrapOperationError wraps err with the name and key of the command
+func wrapOperationError(cmd interface{}, err error) error {
+	return &OperationError{
+		Op:  commandName(cmd),
+		Key: commandKey(cmd),
+		Err: err,
+	}
+}
+
+// TransportError reports a failure while exchanging a command with the server.
+// WroteFully tells whether the whole command frame was written before the
+// failure: if false the server cannot have received the command, if true it
+// may have been applied and only the response was lost, so retrying a
+// non-idempotent command is not safe.
+type TransportError struct {
+	WroteFully bool
+	Err        error
+}
+
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnsupportedCommand is returned for a command the server does not
+// implement, when the server answers it with an "unknown command" error or
+// is known not to support it from Commands
+type ErrUnsupportedCommand struct {
+	Command string
+}
+
+func (e ErrUnsupportedCommand) Error() string {
+	return fmt.Sprintf("command %s not supported by server", e.Command)
+}
+
+// ErrUnsupportedCapability is returned when the server capabilities, as
+// reported by Capabilities, lack a feature the command needs
+type ErrUnsupportedCapability struct {
+	Capability string
+}
+
+func (e ErrUnsupportedCapability) Error() string {
+	return fmt.Sprintf("capability %s not supported by server", e.Capability)
+}
+
+// ServerError is an error response returned by the server
+type ServerError struct {
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error: %s", e.Message)
+}
+
+// unknownCommand reports whether the server rejected the command as unknown
+// with an {"Error": "unknown command ..."} or "unknown variant" response.
+// This assumes a server that answers unknown commands. The server in this
+// repository instead closes the connection on any frame it cannot parse,
+// which surfaces as a *TransportError, not ErrUnsupportedCommand.
+func (e *ServerError) unknownCommand() bool {
+	msg := strings.ToLower(e.Message)
+	return strings.Contains(msg, "unknown command") || strings.Contains(msg, "unknown variant")
+}
+
+// serverBusy reports whether a response is a {"Busy": ...} backpressure
+// response
+func serverBusy(resp interface{}) bool {
+	m, ok := resp.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, busy := m["Busy"]
+	return busy
+}
+
+// classifyServerError turns an "unknown command" server error into
+// ErrUnsupportedCommand, so that callers can fall back gracefully
+func classifyServerError(cmd interface{}, err error) error {
+	var serr *ServerError
+	if errors.As(err, &serr) && serr.unknownCommand() {
+		return ErrUnsupportedCommand{Command: commandName(cmd)}
+	}
+	return err
+}
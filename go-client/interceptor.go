@@ -0,0 +1,19 @@
+package client
+
+import "context"
+
+// Invoker sends a command and returns the raw server response
+type Invoker func(ctx context.Context, cmd interface{}) (interface{}, error)
+
+// Interceptor wraps an Invoker to add cross-cutting behavior such as
+// logging, metrics or retries
+type Interceptor func(next Invoker) Invoker
+
+// chainInterceptors composes interceptors around the final invoker
+func chainInterceptors(interceptors []Interceptor, final Invoker) Invoker {
+	invoker := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		invoker = interceptors[i](invoker)
+	}
+	return invoker
+}
@@ -0,0 +1,138 @@
+// Package jsonvaulttest provides helpers to test code using the client
+// without a live server, by recording the frames exchanged with a real
+// server and replaying them later.
+package jsonvaulttest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+
+	client "github.com/sandrolain/rust-json-db-client"
+)
+
+// Frame directions
+const (
+	Sent     = "send"
+	Received = "recv"
+)
+
+// Frame is a recorded protocol frame. Conn numbers the connections in the
+// order they were opened, and Dir is Sent or Received.
+type Frame struct {
+	Conn int             `json:"conn"`
+	Dir  string          `json:"dir"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Recorder opens connections to a server and logs every frame sent and
+// received on them, one JSON Frame per line, in the format read by
+// NewReplayer. Frames are assumed to use the default byte order.
+type Recorder struct {
+	dial func(ctx context.Context) (net.Conn, error)
+
+	mu    sync.Mutex
+	enc   *json.Encoder
+	conns int
+	err   error
+}
+
+// NewRecorder creates a recorder dialing the specified address and writing
+// the recording to w
+func NewRecorder(address string, w io.Writer) *Recorder {
+	var dialer net.Dialer
+	return &Recorder{
+		dial: func(ctx context.Context) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", address)
+		},
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Option returns the client option routing connections through the recorder
+func (r *Recorder) Option() client.Option {
+	return client.WithConnFactory(r.Dial)
+}
+
+// Dial opens a recorded connection to the server
+func (r *Recorder) Dial(ctx context.Context) (net.Conn, error) {
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	id := r.conns
+	r.conns++
+	r.mu.Unlock()
+
+	return &recordingConn{Conn: conn, recorder: r, id: id}, nil
+}
+
+// Err returns the first error that occurred writing the recording
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// record writes a frame to the recording
+func (r *Recorder) record(id int, dir string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = r.enc.Encode(Frame{Conn: id, Dir: dir, Data: data})
+	}
+}
+
+// recordingConn records the frames written and read on a connection
+type recordingConn struct {
+	net.Conn
+	recorder *Recorder
+	id       int
+
+	sent     frameBuffer
+	received frameBuffer
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	for _, frame := range c.sent.feed(p[:n]) {
+		c.recorder.record(c.id, Sent, frame)
+	}
+	return n, err
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	for _, frame := range c.received.feed(p[:n]) {
+		c.recorder.record(c.id, Received, frame)
+	}
+	return n, err
+}
+
+// frameBuffer reassembles length-prefixed frames from a byte stream
+type frameBuffer struct {
+	buf []byte
+}
+
+// feed appends p to the buffer and returns the frames it completes
+func (b *frameBuffer) feed(p []byte) [][]byte {
+	b.buf = append(b.buf, p...)
+
+	var frames [][]byte
+	for len(b.buf) >= 4 {
+		length := uint64(client.DefaultByteOrder.Uint32(b.buf))
+		if uint64(len(b.buf)-4) < length {
+			break
+		}
+		frame := make([]byte, length)
+		copy(frame, b.buf[4:])
+		frames = append(frames, frame)
+		b.buf = b.buf[4+length:]
+	}
+	return frames
+}
@@ -0,0 +1,63 @@
+package jsonvaulttest
+
+import (
+	"bytes"
+	"testing"
+
+	client "github.com/sandrolain/rust-json-db-client"
+)
+
+// frame returns data with its length prefix
+func frame(data string) []byte {
+	b := make([]byte, 4+len(data))
+	client.DefaultByteOrder.PutUint32(b, uint32(len(data)))
+	copy(b[4:], data)
+	return b
+}
+
+func TestFrameBufferFeed(t *testing.T) {
+	want := []string{`{"Get":{"key":"a"}}`, ``, `{"Ok":1}`}
+	var stream []byte
+	for _, data := range want {
+		stream = append(stream, frame(data)...)
+	}
+
+	// Every chunk size exercises frames and length prefixes split across
+	// reads, and several frames completed by a single read
+	for size := 1; size <= len(stream); size++ {
+		var b frameBuffer
+		var got [][]byte
+		for start := 0; start < len(stream); start += size {
+			end := start + size
+			if end > len(stream) {
+				end = len(stream)
+			}
+			got = append(got, b.feed(stream[start:end])...)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("chunk size %d: got %d frames, want %d", size, len(got), len(want))
+		}
+		for i := range want {
+			if !bytes.Equal(got[i], []byte(want[i])) {
+				t.Errorf("chunk size %d: frame %d = %q, want %q", size, i, got[i], want[i])
+			}
+		}
+		if len(b.buf) != 0 {
+			t.Errorf("chunk size %d: %d bytes left in buffer", size, len(b.buf))
+		}
+	}
+}
+
+func TestFrameBufferFeedIncomplete(t *testing.T) {
+	var b frameBuffer
+	data := frame(`{"Ok":null}`)
+
+	if got := b.feed(data[:len(data)-1]); len(got) != 0 {
+		t.Fatalf("got %d frames from an incomplete frame", len(got))
+	}
+	got := b.feed(data[len(data)-1:])
+	if len(got) != 1 || string(got[0]) != `{"Ok":null}` {
+		t.Fatalf("got %q, want the completed frame", got)
+	}
+}
@@ -0,0 +1,165 @@
+package jsonvaulttest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	client "github.com/sandrolain/rust-json-db-client"
+)
+
+// ErrNoMoreConnections is returned when the client opens more connections
+// than the recording contains
+var ErrNoMoreConnections = errors.New("jsonvaulttest: no more recorded connections")
+
+// Replayer is a fake server that plays back a recording. Each connection
+// the client opens is served with the next recorded connection: every
+// recorded sent frame must match what the client writes, and every received
+// frame is written back in order.
+//
+// Replay is deterministic only for sequential use of each connection;
+// interleaved concurrent commands are not supported.
+type Replayer struct {
+	conns [][]Frame
+
+	mu   sync.Mutex
+	next int
+	err  error
+}
+
+// NewReplayer loads a recording written by a Recorder
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	rep := &Replayer{}
+
+	dec := json.NewDecoder(r)
+	for {
+		var frame Frame
+		if err := dec.Decode(&frame); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("jsonvaulttest: failed to read recording: %w", err)
+		}
+		if frame.Conn < 0 {
+			return nil, fmt.Errorf("jsonvaulttest: invalid connection number %d", frame.Conn)
+		}
+
+		for len(rep.conns) <= frame.Conn {
+			rep.conns = append(rep.conns, nil)
+		}
+		rep.conns[frame.Conn] = append(rep.conns[frame.Conn], frame)
+	}
+
+	return rep, nil
+}
+
+// Option returns the client option connecting to the replayer
+func (r *Replayer) Option() client.Option {
+	return client.WithConnFactory(r.Dial)
+}
+
+// Dial returns an in-memory connection served with the next recorded
+// connection
+func (r *Replayer) Dial(ctx context.Context) (net.Conn, error) {
+	r.mu.Lock()
+	if r.next >= len(r.conns) {
+		r.mu.Unlock()
+		return nil, ErrNoMoreConnections
+	}
+	id := r.next
+	r.next++
+	r.mu.Unlock()
+
+	clientConn, serverConn := net.Pipe()
+	go r.serve(serverConn, id)
+	return clientConn, nil
+}
+
+// Err returns the first mismatch between the client and the recording
+func (r *Replayer) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// serve plays back a recorded connection
+func (r *Replayer) serve(conn net.Conn, id int) {
+	defer conn.Close()
+
+	for _, frame := range r.conns[id] {
+		switch frame.Dir {
+		case Sent:
+			got, err := readFrame(conn)
+			if err != nil {
+				r.fail(fmt.Errorf("connection %d: %w", id, err))
+				return
+			}
+			if !equalJSON(got, frame.Data) {
+				r.fail(fmt.Errorf("connection %d: unexpected frame %s, want %s", id, got, frame.Data))
+				return
+			}
+		case Received:
+			if err := writeFrame(conn, frame.Data); err != nil {
+				r.fail(fmt.Errorf("connection %d: %w", id, err))
+				return
+			}
+		default:
+			r.fail(fmt.Errorf("connection %d: invalid frame direction %q", id, frame.Dir))
+			return
+		}
+	}
+
+	// Anything the client sends after the end of the recording is unexpected
+	if got, err := readFrame(conn); err == nil {
+		r.fail(fmt.Errorf("connection %d: unexpected frame %s after end of recording", id, got))
+	}
+}
+
+// fail records the first replay error
+func (r *Replayer) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = fmt.Errorf("jsonvaulttest: %w", err)
+	}
+}
+
+// readFrame reads a length-prefixed frame
+func readFrame(conn net.Conn) ([]byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(conn, prefix[:]); err != nil {
+		return nil, fmt.Errorf("failed to read frame length: %w", err)
+	}
+
+	data := make([]byte, client.DefaultByteOrder.Uint32(prefix[:]))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return nil, fmt.Errorf("failed to read frame: %w", err)
+	}
+	return data, nil
+}
+
+// writeFrame writes a length-prefixed frame
+func writeFrame(conn net.Conn, data []byte) error {
+	frame := make([]byte, 4+len(data))
+	client.DefaultByteOrder.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+
+	if _, err := conn.Write(frame); err != nil {
+		return fmt.Errorf("failed to write frame: %w", err)
+	}
+	return nil
+}
+
+// equalJSON compares two JSON documents ignoring insignificant whitespace
+func equalJSON(a, b []byte) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// key applies the configured key transformations to a key before it is sent
+func (c *Client) key(key string) string {
+	if c.keyNormalizer != nil {
+		key = c.keyNormalizer(key)
+	}
+	return c.keyPrefix + key
+}
+
+// keyMap applies key transformations to the keys of a map
+func (c *Client) keyMap(m map[string]interface{}) map[string]interface{} {
+	if c.keyNormalizer == nil && c.keyPrefix == "" {
+		return m
+	}
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[c.key(k)] = v
+	}
+	return result
+}
+
+// unkey strips the configured key prefix from a key returned by the server
+func (c *Client) unkey(key string) string {
+	return strings.TrimPrefix(key, c.keyPrefix)
+}
+
+// unkeys strips the configured key prefix from a list of keys
+func (c *Client) unkeys(keys []string) []string {
+	if c.keyPrefix == "" {
+		return keys
+	}
+	for i, key := range keys {
+		keys[i] = c.unkey(key)
+	}
+	return keys
+}
+
+// IntKey builds a key from a prefix and a number, zero-padded to the width
+// set with WithNumericKeyFormat, e.g. "item:0000123". Keys of negative
+// numbers or of numbers wider than the width do not sort numerically.
+func (c *Client) IntKey(prefix string, n int64) string {
+	return fmt.Sprintf("%s%0*d", prefix, c.keyWidth, n)
+}
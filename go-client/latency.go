@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// LatencySummary describes the latency distribution of a command type.
+// Percentiles are estimated with a relative error below 10%.
+type LatencySummary struct {
+	Count uint64
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+const (
+	// latencyMin is the upper bound of the first histogram bucket
+	latencyMin = time.Microsecond
+	// latencyGrowth is the ratio between consecutive bucket bounds
+	latencyGrowth = 1.1
+	// latencyBuckets covers latencies up to about 20 minutes
+	latencyBuckets = 220
+)
+
+// latencyHistogram counts latencies in exponentially growing buckets, a
+// fixed-size streaming estimator of their quantiles
+type latencyHistogram struct {
+	count   uint64
+	buckets [latencyBuckets]uint64
+}
+
+// record adds a latency to the histogram
+func (h *latencyHistogram) record(d time.Duration) {
+	i := 0
+	if d > latencyMin {
+		i = int(math.Ceil(math.Log(float64(d)/float64(latencyMin)) / math.Log(latencyGrowth)))
+		if i >= latencyBuckets {
+			i = latencyBuckets - 1
+		}
+	}
+	h.buckets[i]++
+	h.count++
+}
+
+// quantile returns the upper bound of the bucket holding the q-quantile
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	var seen uint64
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank && n > 0 {
+			return time.Duration(float64(latencyMin) * math.Pow(latencyGrowth, float64(i)))
+		}
+	}
+	return 0
+}
+
+// latencyTracker records command latencies per command type
+type latencyTracker struct {
+	mu         sync.Mutex
+	histograms map[string]*latencyHistogram
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{histograms: make(map[string]*latencyHistogram)}
+}
+
+// interceptor measures the latency of every command it wraps
+func (t *latencyTracker) interceptor(next Invoker) Invoker {
+	return func(ctx context.Context, cmd interface{}) (interface{}, error) {
+		start := time.Now()
+		resp, err := next(ctx, cmd)
+		t.record(commandName(cmd), time.Since(start))
+		return resp, err
+	}
+}
+
+func (t *latencyTracker) record(command string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.histograms[command]
+	if h == nil {
+		h = &latencyHistogram{}
+		t.histograms[command] = h
+	}
+	h.record(d)
+}
+
+// LatencyStats returns the latency percentiles measured per command name
+// since the client was created, when WithLatencyStats is set. Pipelined,
+// streamed and asynchronous commands are not measured.
+func (c *Client) LatencyStats() map[string]LatencySummary {
+	if c.latency == nil {
+		return nil
+	}
+
+	c.latency.mu.Lock()
+	defer c.latency.mu.Unlock()
+
+	stats := make(map[string]LatencySummary, len(c.latency.histograms))
+	for command, h := range c.latency.histograms {
+		stats[command] = LatencySummary{
+			Count: h.count,
+			P50:   h.quantile(0.50),
+			P95:   h.quantile(0.95),
+			P99:   h.quantile(0.99),
+		}
+	}
+	return stats
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDuplicateKey is returned by MSet with WithMSetRejectDuplicates when a
+// key appears more than once in the input
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// MSetCommand represents a MSET command
+type MSetCommand struct {
+	MSet MSetData `json:"MSet"`
+}
+
+type MSetData struct {
+	Items []MSetItem `json:"items"`
+}
+
+type MSetItem struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// KeyValue is a key/value pair
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// MSetOption configures a single MSet call
+type MSetOption func(*msetOptions)
+
+type msetOptions struct {
+	rejectDuplicates bool
+}
+
+// WithMSetRejectDuplicates makes MSet fail with ErrDuplicateKey, writing
+// nothing, when a key appears more than once in the input
+func WithMSetRejectDuplicates() MSetOption {
+	return func(o *msetOptions) {
+		o.rejectDuplicates = true
+	}
+}
+
+// MSet sets multiple keys in a single round trip. When a key appears more
+// than once the last value wins, unless WithMSetRejectDuplicates is given.
+// Keys are compared after WithKeyNormalizer and WithKeyPrefix are applied.
+func (c *Client) MSet(pairs []KeyValue, opts ...MSetOption) error {
+	var o msetOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cmd := MSetCommand{
+		MSet: MSetData{
+			Items: make([]MSetItem, 0, len(pairs)),
+		},
+	}
+
+	// Index of each key in Items, so that a later duplicate replaces the
+	// earlier value in place
+	seen := make(map[string]int, len(pairs))
+	for _, pair := range pairs {
+		if err := c.checkValue(cmd, pair.Value); err != nil {
+			return err
+		}
+		key := c.key(pair.Key)
+		if i, ok := seen[key]; ok {
+			if o.rejectDuplicates {
+				return wrapOperationError(cmd, fmt.Errorf("%w: %q", ErrDuplicateKey, pair.Key))
+			}
+			cmd.MSet.Items[i].Value = pair.Value
+			continue
+		}
+		seen[key] = len(cmd.MSet.Items)
+		cmd.MSet.Items = append(cmd.MSet.Items, MSetItem{Key: key, Value: pair.Value})
+	}
+
+	_, err := c.call(cmd)
+	return err
+}
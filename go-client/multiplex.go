@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// multiplexedRequest is the envelope of a command sent in multiplexed mode
+type multiplexedRequest struct {
+	ID      uint64          `json:"id"`
+	Command json.RawMessage `json:"command"`
+}
+
+// demuxResult is a response, or a failure, delivered to a waiting caller
+type demuxResult struct {
+	resp interface{}
+	err  error
+}
+
+// demux dispatches the responses read from a multiplexed connection to the
+// callers waiting for them, by request ID
+type demux struct {
+	reader *Client
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan demuxResult
+	err     error
+}
+
+// startDemux starts the background reader of a new multiplexed connection.
+// The caller must hold c.mu.
+func (c *Client) startDemux(conn net.Conn) {
+	d := &demux{
+		reader:  c.derive(conn),
+		pending: make(map[uint64]chan demuxResult),
+	}
+	c.demux = d
+
+	go func() {
+		err := d.readLoop()
+
+		// The connection is unusable: the next command dials a new one
+		c.mu.Lock()
+		if c.demux == d {
+			c.dropConn()
+		}
+		c.mu.Unlock()
+
+		d.failAll(err)
+	}()
+}
+
+// invokeMultiplexed sends a command tagged with a request ID and waits for
+// the matching response, letting other commands share the connection
+func (c *Client) invokeMultiplexed(ctx context.Context, cmd interface{}, data []byte) (interface{}, error) {
+	c.mu.Lock()
+
+	if err := c.checkSupported(cmd); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	if err := c.checkCapability(cmd); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	if err := c.dialWithReconnect(ctx); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	d := c.demux
+	id, ch, err := d.register()
+	if err != nil {
+		c.mu.Unlock()
+		return nil, &TransportError{WroteFully: false, Err: err}
+	}
+
+	frame, err := json.Marshal(multiplexedRequest{ID: id, Command: data})
+	if err == nil {
+		err = c.writeFrame(frame)
+	}
+	if err != nil {
+		d.unregister(id)
+		c.dropConn()
+		c.mu.Unlock()
+		return nil, &TransportError{WroteFully: false, Err: err}
+	}
+	c.mu.Unlock()
+
+	select {
+	case result := <-ch:
+		if result.err != nil {
+			return nil, &TransportError{WroteFully: true, Err: result.err}
+		}
+		c.handleWarnings(commandName(cmd), result.resp)
+		return result.resp, nil
+	case <-ctx.Done():
+		d.unregister(id)
+		return nil, ctx.Err()
+	}
+}
+
+// register allocates a request ID and the channel its response is sent to
+func (d *demux) register() (uint64, chan demuxResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	d.nextID++
+	ch := make(chan demuxResult, 1)
+	d.pending[d.nextID] = ch
+	return d.nextID, ch, nil
+}
+
+// unregister forgets a request whose caller stopped waiting
+func (d *demux) unregister(id uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, id)
+}
+
+// readLoop reads response frames and dispatches them until the connection fails
+func (d *demux) readLoop() error {
+	// The connection is idle whenever no request is in flight
+	d.reader.allowIdleReads()
+	for {
+		resp, err := d.reader.readResponse()
+		if err != nil {
+			return err
+		}
+
+		id, payload, err := multiplexedResponse(resp)
+		if err != nil {
+			return err
+		}
+
+		d.mu.Lock()
+		ch, ok := d.pending[id]
+		delete(d.pending, id)
+		d.mu.Unlock()
+
+		// Responses for abandoned requests are dropped
+		if ok {
+			ch <- demuxResult{resp: payload}
+		}
+	}
+}
+
+// failAll fails every pending request and any later registration
+func (d *demux) failAll(err error) {
+	if err == nil {
+		err = errors.New("connection closed")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.err = err
+	for id, ch := range d.pending {
+		ch <- demuxResult{err: err}
+		delete(d.pending, id)
+	}
+}
+
+// multiplexedResponse extracts the request ID and the response from a
+// {"id": n, "response": ...} frame
+func multiplexedResponse(resp interface{}) (uint64, interface{}, error) {
+	m, ok := resp.(map[string]interface{})
+	if !ok {
+		return 0, nil, fmt.Errorf("unexpected multiplexed frame: %v", resp)
+	}
+	id, ok := m["id"].(float64)
+	if !ok {
+		return 0, nil, fmt.Errorf("missing request id in frame: %v", resp)
+	}
+	return uint64(id), m["response"], nil
+}
@@ -0,0 +1,273 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithInterceptor adds an interceptor around every command sent by the client.
+// Interceptors run in the order they are registered, the first being the outermost.
+func WithInterceptor(interceptor Interceptor) Option {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptor)
+	}
+}
+
+// WithLatencyStats tracks per-command latency percentiles, reported by
+// LatencyStats. Latencies are measured by an interceptor added at this
+// point of the chain, so they include the interceptors added after it.
+func WithLatencyStats() Option {
+	return func(c *Client) {
+		if c.latency == nil {
+			c.latency = newLatencyTracker()
+			c.interceptors = append(c.interceptors, c.latency.interceptor)
+		}
+	}
+}
+
+// WithRateLimiter registers a limiter for a class of commands.
+// By default each command is its own class, named after the command (e.g. "QGet").
+func WithRateLimiter(class string, limiter RateLimiter) Option {
+	return func(c *Client) {
+		if c.rateLimiters == nil {
+			c.rateLimiters = make(map[string]RateLimiter)
+		}
+		c.rateLimiters[class] = limiter
+	}
+}
+
+// WithCommandClass groups commands under a class so they share a rate limiter
+func WithCommandClass(class string, commands ...CommandType) Option {
+	return func(c *Client) {
+		if c.commandClasses == nil {
+			c.commandClasses = make(map[CommandType]string)
+		}
+		for _, command := range commands {
+			c.commandClasses[command] = class
+		}
+	}
+}
+
+// WithWarningHandler registers a callback invoked with the warnings the
+// server attaches to otherwise successful responses
+func WithWarningHandler(handler func(command string, warnings []string)) Option {
+	return func(c *Client) {
+		c.warningHandler = handler
+	}
+}
+
+// WithNetwork sets the network used to dial the server, e.g. "tcp4" or "tcp6".
+// The default is "tcp".
+func WithNetwork(network string) Option {
+	return func(c *Client) {
+		c.network = network
+	}
+}
+
+// WithClientName identifies every connection the client opens to the
+// server with name, e.g. for server logs and connection listings. If the
+// server closes the connection on SetName, as servers that do not know it
+// do, the client connects again and stops sending the name.
+func WithClientName(name string) Option {
+	return func(c *Client) {
+		c.clientName = name
+	}
+}
+
+// WithConnFactory sets a function used to open connections instead of
+// dialing the address, e.g. to return a net.Pipe in tests or a wrapped,
+// instrumented or pre-authenticated connection. The address and network
+// are then ignored.
+func WithConnFactory(factory func(ctx context.Context) (net.Conn, error)) Option {
+	return func(c *Client) {
+		c.connFactory = factory
+	}
+}
+
+// WithEncoder sets how command envelopes are built, for servers that do not
+// use the default ExternallyTagged format
+func WithEncoder(encoder Encoder) Option {
+	return func(c *Client) {
+		c.encoder = encoder
+	}
+}
+
+// WithReadBuffer sets the size of the operating system receive buffer of
+// TCP connections, for high-throughput transfers
+func WithReadBuffer(bytes int) Option {
+	return func(c *Client) {
+		c.readBuffer = bytes
+	}
+}
+
+// WithWriteBuffer sets the size of the operating system send buffer of TCP
+// connections, for high-throughput transfers
+func WithWriteBuffer(bytes int) Option {
+	return func(c *Client) {
+		c.writeBuffer = bytes
+	}
+}
+
+// WithMaxResponseSize sets the largest response frame the client accepts.
+// Larger length prefixes are treated as a protocol error.
+func WithMaxResponseSize(size uint32) Option {
+	return func(c *Client) {
+		c.maxResponseSize = size
+	}
+}
+
+// WithPushHandler registers a callback for out-of-band messages the server
+// pushes on a command, streaming or subscription connection, framed as
+// {"Push": ...}. Without a handler these messages are discarded.
+func WithPushHandler(handler func(msg interface{})) Option {
+	return func(c *Client) {
+		c.pushHandler = handler
+	}
+}
+
+// WithKeyNormalizer applies fn to every key before it is sent to the server,
+// e.g. strings.ToLower to make lookups case-insensitive
+func WithKeyNormalizer(fn func(string) string) Option {
+	return func(c *Client) {
+		c.keyNormalizer = fn
+	}
+}
+
+// WithNumericKeyFormat zero-pads the numbers of keys built with IntKey to
+// width digits, so that lexicographic scans follow numeric order
+func WithNumericKeyFormat(width int) Option {
+	return func(c *Client) {
+		c.keyWidth = width
+	}
+}
+
+// WithByteOrder sets the byte order of the frame length prefix.
+// The default is DefaultByteOrder (big-endian).
+func WithByteOrder(order binary.ByteOrder) Option {
+	return func(c *Client) {
+		c.byteOrder = order
+	}
+}
+
+// WithDebugWriter dumps every outgoing command frame and incoming response
+// frame to w as indented JSON, for protocol debugging. Writes to w are
+// serialized, including those of the connections used by SetAsync and
+// subscriptions.
+func WithDebugWriter(w io.Writer) Option {
+	return func(c *Client) {
+		c.debugWriter = w
+		c.debugMu = &sync.Mutex{}
+	}
+}
+
+// WithQueryCache caches QGet results per key and query for ttl, keeping at
+// most maxEntries of them. Invalidation is only time-based: writes are not
+// seen until the cached result expires, or ClearCache is called.
+func WithQueryCache(ttl time.Duration, maxEntries int) Option {
+	return func(c *Client) {
+		c.queryCache = newQueryCache(ttl, maxEntries)
+	}
+}
+
+// WithTypeField sets the document field read by GetTyped to pick the
+// registered type. The default is "type".
+func WithTypeField(field string) Option {
+	return func(c *Client) {
+		c.typeField = field
+	}
+}
+
+// WithDryRun sends every write command (Set, Merge, QSet, Delete, ...) with
+// a dry-run flag: the server validates it and reports what would have
+// happened without applying it. Methods returning a value, such as
+// MergeCount or GetDelete, return the simulated one. A server ignoring the
+// flag would apply the writes, so they fail with ErrUnsupportedCapability
+// until Capabilities has reported dry-run support.
+func WithDryRun() Option {
+	return func(c *Client) {
+		c.dryRun = true
+	}
+}
+
+// WithRejectNilValues makes Set, Merge and QSet return ErrNilValue for a
+// nil value, which would otherwise store null, to catch programming errors.
+// It covers their pipelined and asynchronous variants too: a rejected
+// pipelined value makes Exec fail without sending anything.
+func WithRejectNilValues() Option {
+	return func(c *Client) {
+		c.rejectNil = true
+	}
+}
+
+// WithReadProgress sets a function called while reading response frames
+// larger than 256 KiB, after each chunk, with the bytes read so far and the
+// frame size. Streamed reads such as QGetStream do not report progress.
+func WithReadProgress(progress func(read, total int)) Option {
+	return func(c *Client) {
+		c.readProgress = progress
+	}
+}
+
+// WithMaxReadStall fails a read that receives no data for d, even within a
+// longer operation deadline, so that a server stalling mid-response is
+// detected early. The deadline is refreshed on every read, so large or
+// streamed responses are not limited as long as data keeps flowing.
+// Subscriptions and the multiplexed reader, which wait for data
+// indefinitely, are not affected.
+func WithMaxReadStall(d time.Duration) Option {
+	return func(c *Client) {
+		c.maxReadStall = d
+	}
+}
+
+// WithRetry retries commands that fail with a transport error, up to
+// attempts tries in total, waiting between tries as computed by backoff.
+// Commands that may have reached the server are retried only if idempotent.
+// Idempotent commands rejected with a busy response are retried as well.
+func WithRetry(attempts int, backoff Backoff) Option {
+	return func(c *Client) {
+		c.retry = &retryPolicy{attempts: attempts, backoff: backoff}
+	}
+}
+
+// WithReconnect retries dialing the new connection needed by the next command
+// after a transport error dropped the previous one, making up to attempts
+// tries spaced by backoff
+func WithReconnect(attempts int, backoff Backoff) Option {
+	return func(c *Client) {
+		c.reconnect = &reconnectPolicy{attempts: attempts, backoff: backoff}
+	}
+}
+
+// WithStateListener registers a callback invoked on every connection state
+// transition. It runs synchronously and must not call the client.
+func WithStateListener(listener func(old, new ConnState)) Option {
+	return func(c *Client) {
+		c.stateListener = listener
+	}
+}
+
+// WithKeyPrefix scopes the client to a namespace: prefix is prepended to
+// every key sent and stripped from the keys returned by the server
+func WithKeyPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.keyPrefix = prefix
+	}
+}
+
+// WithMultiplexing enables multiplexed mode: every command is tagged with a
+// request ID echoed by the server, so many commands can be in flight on the
+// same connection at once. The server must support multiplexing. Pipelines
+// and streams are not available in this mode.
+func WithMultiplexing() Option {
+	return func(c *Client) {
+		c.multiplexed = true
+	}
+}
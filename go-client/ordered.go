@@ -0,0 +1,145 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// OrderedValue is a JSON value decoded with object keys in the order the
+// server sent them. Objects are represented as OrderedObject, arrays as
+// []interface{} and scalars as with encoding/json.
+type OrderedValue struct {
+	Value interface{}
+}
+
+// MarshalJSON encodes the value preserving object key order
+func (v *OrderedValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Value)
+}
+
+// OrderedPair is a key/value member of an OrderedObject
+type OrderedPair struct {
+	Key   string
+	Value interface{}
+}
+
+// OrderedObject is a JSON object whose members keep their original order
+type OrderedObject []OrderedPair
+
+// Get returns the value of the member with the given key
+func (o OrderedObject) Get(key string) (interface{}, bool) {
+	for _, pair := range o {
+		if pair.Key == key {
+			return pair.Value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON encodes the object with its members in order
+func (o OrderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, pair := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// GetOrdered retrieves the value for the given key preserving the order of
+// object keys, for deterministic output such as golden files or hashing.
+// The response is decoded from the wire, bypassing the interceptor chain.
+func (c *Client) GetOrdered(key string) (*OrderedValue, error) {
+	cmd := GetCommand{
+		Get: GetData{
+			Key: c.key(key),
+		},
+	}
+
+	body, err := c.openStream(cmd)
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+	var once sync.Once
+	closeFn := func() {
+		once.Do(func() {
+			c.closeStream(body)
+		})
+	}
+	defer closeFn()
+
+	decoder := json.NewDecoder(body)
+	if err := enterOkValue(decoder); err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return nil, wrapOperationError(cmd, err)
+	}
+	return &OrderedValue{Value: value}, nil
+}
+
+// decodeOrdered reads the next JSON value from the decoder, keeping the
+// order of object members
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ordered value: %w", err)
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		object := OrderedObject{}
+		for decoder.More() {
+			keyTok, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode ordered value: %w", err)
+			}
+			key, ok := keyTok.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object key: %v", keyTok)
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, OrderedPair{Key: key, Value: value})
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("failed to decode ordered value: %w", err)
+		}
+		return object, nil
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("failed to decode ordered value: %w", err)
+		}
+		return array, nil
+	default:
+		return tok, nil
+	}
+}
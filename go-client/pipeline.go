@@ -0,0 +1,223 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Pipeline queues commands and sends them in a single batch, reading all the
+// responses afterwards. Pipelined commands bypass the interceptor chain.
+type Pipeline struct {
+	client *Client
+	cmds   []interface{}
+	err    error
+}
+
+// PipelineResult is the outcome of a single pipelined command
+type PipelineResult struct {
+	Value interface{}
+	Err   error
+}
+
+// Pipeline creates a new pipeline on the client connection
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{client: c}
+}
+
+// Set queues a SET command
+func (p *Pipeline) Set(key string, value interface{}) {
+	p.queueValue(SetCommand{
+		Set: SetData{
+			Key:   p.client.key(key),
+			Value: value,
+		},
+	}, value)
+}
+
+// SetCopy queues a SET command with a deep copy of value taken now.
+// Pipelined values are encoded on Exec, so Set would see later mutations.
+func (p *Pipeline) SetCopy(key string, value interface{}) error {
+	cmd := SetCommand{Set: SetData{Key: p.client.key(key)}}
+	if err := p.client.checkValue(cmd, value); err != nil {
+		return err
+	}
+
+	snapshot, err := snapshotValue(value)
+	if err != nil {
+		return err
+	}
+	p.Set(key, snapshot)
+	return nil
+}
+
+// Get queues a GET command
+func (p *Pipeline) Get(key string) {
+	p.cmds = append(p.cmds, GetCommand{
+		Get: GetData{
+			Key: p.client.key(key),
+		},
+	})
+}
+
+// Delete queues a DELETE command
+func (p *Pipeline) Delete(key string) {
+	p.cmds = append(p.cmds, DeleteCommand{
+		Delete: DeleteData{
+			Key: p.client.key(key),
+		},
+	})
+}
+
+// QGet queues a QGET command
+func (p *Pipeline) QGet(key, query string) {
+	p.cmds = append(p.cmds, QGetCommand{
+		QGet: QGetData{
+			Key:   p.client.key(key),
+			Query: query,
+		},
+	})
+}
+
+// QSet queues a QSET command
+func (p *Pipeline) QSet(key, path string, value interface{}) {
+	p.queueValue(QSetCommand{
+		QSet: QSetData{
+			Key:   p.client.key(key),
+			Path:  path,
+			Value: value,
+		},
+	}, value)
+}
+
+// Merge queues a MERGE command
+func (p *Pipeline) Merge(key string, value interface{}) {
+	p.queueValue(MergeCommand{
+		Merge: MergeData{
+			Key:   p.client.key(key),
+			Value: value,
+		},
+	}, value)
+}
+
+// queueValue queues a command writing value. A value rejected by
+// WithRejectNilValues is not queued: the first such error is returned by
+// Exec, which then sends nothing.
+func (p *Pipeline) queueValue(cmd interface{}, value interface{}) {
+	if err := p.client.checkValue(cmd, value); err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return
+	}
+	p.cmds = append(p.cmds, cmd)
+}
+
+// Len returns the number of queued commands
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Exec sends the queued commands and returns their results in order.
+// Server errors are reported per command in PipelineResult.Err.
+//
+// Exec always consumes exactly one response per command written, so the
+// connection is left in a clean state. If a frame cannot be written or read,
+// the remaining responses cannot be accounted for: the connection is dropped
+// and the error is returned, so that a later command cannot read a stale
+// frame. The next command dials a new connection.
+func (p *Pipeline) Exec() ([]PipelineResult, error) {
+	results, err := p.exec(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// exec sends the queued commands, giving up when ctx is done. When reading
+// fails it also returns the results read before the failure.
+func (p *Pipeline) exec(ctx context.Context) ([]PipelineResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cmds, queueErr := p.cmds, p.err
+	p.cmds, p.err = nil, nil
+	if queueErr != nil {
+		return nil, queueErr
+	}
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	// Encode everything up front so that an encoding error leaves nothing in flight
+	frames := make([][]byte, len(cmds))
+	for i, cmd := range cmds {
+		data, err := p.client.encodeCommand(cmd)
+		if err != nil {
+			return nil, wrapOperationError(cmd, err)
+		}
+		frames[i] = data
+	}
+
+	if p.client.multiplexed {
+		return nil, ErrMultiplexed
+	}
+
+	p.client.mu.Lock()
+	defer p.client.mu.Unlock()
+
+	if err := p.client.dialWithReconnect(ctx); err != nil {
+		return nil, wrapOperationError(cmds[0], err)
+	}
+
+	// Interrupt blocked reads and writes when ctx is done. The connection
+	// is kept in a variable as a failure drops it from the client.
+	conn := p.client.conn
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("failed to set deadline: %w", err)
+		}
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer func() {
+		if stop() {
+			conn.SetDeadline(time.Time{})
+		} else if p.client.conn == conn {
+			// The deadline expired while in use: responses may be pending
+			p.client.dropConn()
+		}
+	}()
+
+	for i, data := range frames {
+		if err := p.client.writeFrame(data); err != nil {
+			return nil, p.poison(wrapOperationError(cmds[i], err))
+		}
+	}
+
+	results := make([]PipelineResult, 0, len(cmds))
+	for _, cmd := range cmds {
+		resp, err := p.client.readResponse()
+		if err != nil {
+			return results, p.poison(wrapOperationError(cmd, err))
+		}
+
+		value, err := parseResponse(resp)
+		if err != nil {
+			err = wrapOperationError(cmd, classifyServerError(cmd, err))
+		}
+		results = append(results, PipelineResult{Value: value, Err: err})
+	}
+
+	return results, nil
+}
+
+// poison drops the client connection after an unrecoverable pipeline
+// failure, so that the next command dials a fresh one.
+// The caller must hold the client lock.
+func (p *Pipeline) poison(err error) error {
+	p.client.dropConn()
+	return fmt.Errorf("pipeline aborted, connection dropped: %w", err)
+}
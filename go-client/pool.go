@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPoolClosed is returned when using a pool after Close
+var ErrPoolClosed = errors.New("pool closed")
+
+// ErrPoolTimeout is returned by GetContext when no connection becomes
+// available before the context is done
+var ErrPoolTimeout = errors.New("timed out waiting for a pool connection")
+
+// Pool is a fixed-size pool of client connections to the same server.
+// Connections are dialed lazily on demand, or eagerly with Warmup.
+type Pool struct {
+	address string
+	opts    []Option
+
+	slots chan struct{}
+	idle  chan *Client
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewPool creates a pool of up to size connections to the specified address.
+// The options are applied to every connection.
+func NewPool(address string, size int, opts ...Option) *Pool {
+	return &Pool{
+		address: address,
+		opts:    opts,
+		slots:   make(chan struct{}, size),
+		idle:    make(chan *Client, size),
+	}
+}
+
+// Get returns an idle connection, dialing a new one if the pool is not full,
+// or waits for a connection to be returned
+func (p *Pool) Get() (*Client, error) {
+	return p.GetContext(context.Background())
+}
+
+// GetContext is like Get, but gives up waiting for a free connection when
+// ctx is done, returning ErrPoolTimeout
+func (p *Pool) GetContext(ctx context.Context) (*Client, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+
+	select {
+	case c := <-p.idle:
+		return c, nil
+	case p.slots <- struct{}{}:
+		return p.dial()
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", ErrPoolTimeout, ctx.Err())
+	}
+}
+
+// Put returns a connection to the pool
+func (p *Pool) Put(c *Client) {
+	// Held across the send so that Close either sees the connection in the
+	// idle queue or marks the pool closed first. The send cannot block, as
+	// the queue has room for every connection of the pool.
+	p.mu.Lock()
+	if !p.closed {
+		p.idle <- c
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	p.Discard(c)
+}
+
+// Discard closes a broken connection and frees its slot in the pool
+func (p *Pool) Discard(c *Client) {
+	c.Close()
+	<-p.slots
+}
+
+// Warmup dials every connection not yet open, up to the pool size, and pings
+// them so that the pool is ready before serving traffic
+func (p *Pool) Warmup(ctx context.Context) error {
+	var clients []*Client
+	defer func() {
+		for _, c := range clients {
+			p.Put(c)
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p.isClosed() {
+			return ErrPoolClosed
+		}
+
+		select {
+		case p.slots <- struct{}{}:
+		default:
+			// Every slot already holds a connection
+			return nil
+		}
+
+		c, err := p.dial()
+		if err != nil {
+			return err
+		}
+
+		if _, err := c.callContext(ctx, PingCommand{}); err != nil {
+			p.Discard(c)
+			return err
+		}
+
+		clients = append(clients, c)
+	}
+}
+
+// Close closes the pool and its idle connections. Connections still in use
+// are closed when they are returned.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	for {
+		select {
+		case c := <-p.idle:
+			p.Discard(c)
+		default:
+			return nil
+		}
+	}
+}
+
+// dial opens a new connection for an already acquired slot
+func (p *Pool) dial() (*Client, error) {
+	c, err := NewClient(p.address, p.opts...)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *Pool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
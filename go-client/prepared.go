@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PreparedCommand is a command serialized once and executed many times
+type PreparedCommand struct {
+	name string
+	key  string
+	cmd  interface{}
+	data []byte
+	err  error
+}
+
+// PrepareGet pre-serializes a GET command for the given key, transformed by
+// the key options of c. It must be executed on c, or on a client with the
+// same key options.
+func (c *Client) PrepareGet(key string) PreparedCommand {
+	return prepare(GetCommand{
+		Get: GetData{
+			Key: c.key(key),
+		},
+	})
+}
+
+// prepare serializes a command into a PreparedCommand
+func prepare(cmd interface{}) PreparedCommand {
+	prepared := PreparedCommand{
+		name: commandName(cmd),
+		key:  commandKey(cmd),
+		cmd:  cmd,
+	}
+	prepared.data, prepared.err = json.Marshal(cmd)
+	if prepared.err != nil {
+		prepared.err = fmt.Errorf("failed to marshal command: %w", prepared.err)
+	}
+	return prepared
+}
+
+// Do executes the prepared command on the given client
+func (p PreparedCommand) Do(c *Client) (interface{}, error) {
+	return c.call(p)
+}
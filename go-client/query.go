@@ -0,0 +1,169 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidQuery is returned when building a malformed Query
+var ErrInvalidQuery = errors.New("invalid query")
+
+// identifier matches the field names usable in dot notation
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// filterOperators are the comparison operators accepted by Filter
+var filterOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+// Query builds a JSONPath expression step by step, quoting field names and
+// filter values, so that paths built from user input stay well-formed:
+//
+//	q := client.NewQuery().Root().Field("items").Filter("price", ">", 100).Index(0)
+//	value, err := c.QGet("catalog", q) // $.items[?(@.price > 100)][0]
+//
+// The first error is kept and returned by Build.
+type Query struct {
+	b    strings.Builder
+	root bool
+	err  error
+}
+
+// NewQuery starts an empty query, to be followed by Root
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// Root adds the root element $, which must come first
+func (q *Query) Root() *Query {
+	if q.root {
+		return q.fail("root must appear once, at the start")
+	}
+	q.root = true
+	q.b.WriteString("$")
+	return q
+}
+
+// Field selects a child field
+func (q *Query) Field(name string) *Query {
+	if !q.check() {
+		return q
+	}
+	if name == "" {
+		return q.fail("empty field name")
+	}
+	if identifier.MatchString(name) {
+		q.b.WriteString("." + name)
+	} else {
+		q.b.WriteString("[" + quote(name) + "]")
+	}
+	return q
+}
+
+// Descendant selects the fields with the given name at any depth
+func (q *Query) Descendant(name string) *Query {
+	if !q.check() {
+		return q
+	}
+	if !identifier.MatchString(name) {
+		return q.fail(fmt.Sprintf("invalid descendant name %q", name))
+	}
+	q.b.WriteString(".." + name)
+	return q
+}
+
+// Index selects an array element; negative indexes count from the end
+func (q *Query) Index(i int) *Query {
+	if !q.check() {
+		return q
+	}
+	fmt.Fprintf(&q.b, "[%d]", i)
+	return q
+}
+
+// All selects every element or field
+func (q *Query) All() *Query {
+	if !q.check() {
+		return q
+	}
+	q.b.WriteString("[*]")
+	return q
+}
+
+// Filter selects the array elements whose field compares to value with op,
+// one of ==, !=, <, <=, > and >=
+func (q *Query) Filter(field, op string, value interface{}) *Query {
+	if !q.check() {
+		return q
+	}
+	if !identifier.MatchString(field) {
+		return q.fail(fmt.Sprintf("invalid filter field %q", field))
+	}
+	if !filterOperators[op] {
+		return q.fail(fmt.Sprintf("invalid filter operator %q", op))
+	}
+	literal, err := json.Marshal(value)
+	if err != nil {
+		return q.fail(fmt.Sprintf("invalid filter value: %v", err))
+	}
+	fmt.Fprintf(&q.b, "[?(@.%s %s %s)]", field, op, literal)
+	return q
+}
+
+// Build returns the JSONPath expression, or the first error met while
+// building it
+func (q *Query) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	if !q.root {
+		return "", fmt.Errorf("%w: missing root", ErrInvalidQuery)
+	}
+	return q.b.String(), nil
+}
+
+// String returns the expression built so far
+func (q *Query) String() string {
+	return q.b.String()
+}
+
+// check reports whether a step can be added
+func (q *Query) check() bool {
+	if q.err != nil {
+		return false
+	}
+	if !q.root {
+		q.fail("query must start with root")
+		return false
+	}
+	return true
+}
+
+// fail records the first building error
+func (q *Query) fail(msg string) *Query {
+	if q.err == nil {
+		q.err = fmt.Errorf("%w: %s", ErrInvalidQuery, msg)
+	}
+	return q
+}
+
+// quote returns a single-quoted JSONPath string literal
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// queryString resolves a query given as a string or a *Query
+func queryString(query interface{}) (string, error) {
+	switch q := query.(type) {
+	case string:
+		return q, nil
+	case *Query:
+		return q.Build()
+	default:
+		return "", fmt.Errorf("%w: unsupported query type %T", ErrInvalidQuery, query)
+	}
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQueryBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{"filter and index", NewQuery().Root().Field("items").Filter("price", ">", 100).Index(0), `$.items[?(@.price > 100)][0]`},
+		{"quoted field", NewQuery().Root().Field("odd key's").Descendant("x").All().Filter("n", "==", `a"b`), `$['odd key\'s']..x[*][?(@.n == "a\"b")]`},
+		{"backslash", NewQuery().Root().Field(`a\b`), `$['a\\b']`},
+		{"root only", NewQuery().Root(), `$`},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.query.Build()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQueryBuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{"missing root", NewQuery().Field("a"), "invalid query: query must start with root"},
+		{"empty", NewQuery(), "invalid query: missing root"},
+		{"operator", NewQuery().Root().Filter("n", "~", 1), `invalid query: invalid filter operator "~"`},
+		{"filter field", NewQuery().Root().Filter("a b", "==", 1), `invalid query: invalid filter field "a b"`},
+		{"first error wins", NewQuery().Root().Filter("n", "~", 1).Filter("a b", "==", 1), `invalid query: invalid filter operator "~"`},
+	}
+
+	for _, tt := range tests {
+		_, err := tt.query.Build()
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: got %v, want ErrInvalidQuery", tt.name, err)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// queryCache is a client-side LRU cache of QGet results with a fixed TTL
+type queryCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[queryCacheKey]*list.Element
+	lru     *list.List
+}
+
+type queryCacheKey struct {
+	key   string
+	query string
+}
+
+type queryCacheEntry struct {
+	id      queryCacheKey
+	value   interface{}
+	expires time.Time
+}
+
+func newQueryCache(ttl time.Duration, maxEntries int) *queryCache {
+	return &queryCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[queryCacheKey]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// get returns a copy of the cached result, if present and not expired
+func (q *queryCache) get(key, query string) (interface{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	elem, ok := q.entries[queryCacheKey{key, query}]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*queryCacheEntry)
+	if time.Now().After(entry.expires) {
+		q.remove(elem)
+		return nil, false
+	}
+
+	q.lru.MoveToFront(elem)
+	return copyValue(entry.value), true
+}
+
+// put stores a copy of a result, evicting the least recently used entry
+// when the cache is full
+func (q *queryCache) put(key, query string, value interface{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	id := queryCacheKey{key, query}
+	entry := &queryCacheEntry{id: id, value: copyValue(value), expires: time.Now().Add(q.ttl)}
+	if elem, ok := q.entries[id]; ok {
+		elem.Value = entry
+		q.lru.MoveToFront(elem)
+		return
+	}
+
+	q.entries[id] = q.lru.PushFront(entry)
+	for q.lru.Len() > q.maxEntries {
+		q.remove(q.lru.Back())
+	}
+}
+
+// clear drops every entry
+func (q *queryCache) clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.entries = make(map[queryCacheKey]*list.Element)
+	q.lru.Init()
+}
+
+// remove drops an entry. The caller must hold q.mu.
+func (q *queryCache) remove(elem *list.Element) {
+	q.lru.Remove(elem)
+	delete(q.entries, elem.Value.(*queryCacheEntry).id)
+}
+
+// copyValue deep-copies a decoded JSON value, so that callers modifying a
+// result cannot alter the cached one
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = copyValue(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = copyValue(item)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// ClearCache drops every result cached by WithQueryCache
+func (c *Client) ClearCache() {
+	if c.queryCache != nil {
+		c.queryCache.clear()
+	}
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// RateLimiter throttles commands before they are sent.
+// *rate.Limiter from golang.org/x/time/rate satisfies this interface.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// waitRateLimit blocks on the limiter registered for the command class, if any
+func (c *Client) waitRateLimit(ctx context.Context, command CommandType) error {
+	if len(c.rateLimiters) == 0 {
+		return nil
+	}
+
+	class := command.String()
+	if mapped, ok := c.commandClasses[command]; ok {
+		class = mapped
+	}
+
+	limiter, ok := c.rateLimiters[class]
+	if !ok {
+		return nil
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit wait for %s: %w", class, err)
+	}
+	return nil
+}
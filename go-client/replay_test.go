@@ -0,0 +1,449 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	client "github.com/sandrolain/rust-json-db-client"
+	"github.com/sandrolain/rust-json-db-client/jsonvaulttest"
+)
+
+// replay returns a lazy client connected to a replay of recording
+func replay(t *testing.T, recording string, opts ...client.Option) (*client.Client, *jsonvaulttest.Replayer) {
+	t.Helper()
+
+	rep, err := jsonvaulttest.NewReplayer(strings.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.NewLazyClient("replay", append([]client.Option{rep.Option()}, opts...)...)
+	t.Cleanup(func() { c.Close() })
+	return c, rep
+}
+
+// closingDial returns a connection to a server that reads one frame and
+// closes without answering
+func closingDial(ctx context.Context) (net.Conn, error) {
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		defer serverConn.Close()
+		var prefix [4]byte
+		if _, err := io.ReadFull(serverConn, prefix[:]); err != nil {
+			return
+		}
+		io.CopyN(io.Discard, serverConn, int64(client.DefaultByteOrder.Uint32(prefix[:])))
+	}()
+	return clientConn, nil
+}
+
+// ackDial returns a connection to a server that answers every frame with
+// a plain acknowledgment
+func ackDial(ctx context.Context) (net.Conn, error) {
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		defer serverConn.Close()
+		ack := []byte(`{"Ok":null}`)
+		reply := make([]byte, 4+len(ack))
+		client.DefaultByteOrder.PutUint32(reply, uint32(len(ack)))
+		copy(reply[4:], ack)
+		for {
+			var prefix [4]byte
+			if _, err := io.ReadFull(serverConn, prefix[:]); err != nil {
+				return
+			}
+			if _, err := io.CopyN(io.Discard, serverConn, int64(client.DefaultByteOrder.Uint32(prefix[:]))); err != nil {
+				return
+			}
+			if _, err := serverConn.Write(reply); err != nil {
+				return
+			}
+		}
+	}()
+	return clientConn, nil
+}
+
+func TestReplayGet(t *testing.T) {
+	c, rep := replay(t, `
+{"conn":0,"dir":"send","data":{"Get":{"key":"a"}}}
+{"conn":0,"dir":"recv","data":{"Ok":{"n":1}}}
+`)
+
+	value, err := c.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := value.(map[string]interface{}); !ok || m["n"] != float64(1) {
+		t.Errorf("got %v", value)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetTTLRequiresCapability(t *testing.T) {
+	c, rep := replay(t, `
+{"conn":0,"dir":"send","data":{"Hello":null}}
+{"conn":0,"dir":"recv","data":{"Ok":{"capabilities":{"ttl":true}}}}
+{"conn":0,"dir":"send","data":{"Set":{"key":"k","value":1,"ttl_ms":60000}}}
+{"conn":0,"dir":"recv","data":{"Ok":null}}
+`)
+
+	// Capabilities are unknown: the TTL could be silently ignored
+	var unsupported client.ErrUnsupportedCapability
+	if err := c.Set("k", 1, client.WithTTL(time.Minute)); !errors.As(err, &unsupported) {
+		t.Fatalf("got %v, want ErrUnsupportedCapability", err)
+	}
+
+	if _, err := c.Capabilities(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("k", 1, client.WithTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetDurabilityRequiresAcknowledgment(t *testing.T) {
+	c, rep := replay(t, `
+{"conn":0,"dir":"send","data":{"Set":{"key":"k","value":1,"durability":"synced"}}}
+{"conn":0,"dir":"recv","data":{"Ok":null}}
+{"conn":0,"dir":"send","data":{"Set":{"key":"k","value":1,"durability":"synced"}}}
+{"conn":0,"dir":"recv","data":{"Ok":{"durability":"synced"}}}
+`)
+
+	// A plain acknowledgment means the server ignored the level
+	if err := c.Set("k", 1, client.WithDurability(client.Synced)); err == nil {
+		t.Fatal("expected an error for an acknowledgment without durability")
+	}
+	if err := c.Set("k", 1, client.WithDurability(client.Synced)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReplaySetStream(t *testing.T) {
+	c, rep := replay(t, `
+{"conn":0,"dir":"send","data":{"Set":{"key":"doc","value":{"items":[1,2]}}}}
+{"conn":0,"dir":"recv","data":{"Ok":null}}
+`)
+
+	value := `{"items":[1,2]}`
+	if err := c.SetStream("doc", strings.NewReader(value), int64(len(value))); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetStreamPeerCloseDropsConnection(t *testing.T) {
+	c := client.NewLazyClient("closing", client.WithConnFactory(closingDial))
+	defer c.Close()
+
+	value := `{"items":[1,2]}`
+	if err := c.SetStream("doc", strings.NewReader(value), int64(len(value))); err == nil {
+		t.Fatal("expected SetStream to fail when the server closes")
+	}
+	if state := c.State(); state != client.Disconnected {
+		t.Errorf("state = %v, want %v", state, client.Disconnected)
+	}
+}
+
+func TestReplayDeadlineDropsConnection(t *testing.T) {
+	// The BRPop reply never comes: the next command must use a new
+	// connection rather than read a late reply on the old one
+	c, rep := replay(t, `
+{"conn":0,"dir":"send","data":{"BRPop":{"key":"jobs","timeout_ms":1000}}}
+{"conn":1,"dir":"send","data":{"Get":{"key":"a"}}}
+{"conn":1,"dir":"recv","data":{"Ok":2}}
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.BRPop(ctx, "jobs", time.Second); err == nil {
+		t.Fatal("expected BRPop to fail when ctx ends")
+	}
+	if state := c.State(); state != client.Disconnected {
+		t.Errorf("state = %v, want %v", state, client.Disconnected)
+	}
+
+	value, err := c.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != float64(2) {
+		t.Errorf("got %v, want 2", value)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReplayOversizedResponseDropsConnection(t *testing.T) {
+	c, _ := replay(t, `
+{"conn":0,"dir":"send","data":{"Get":{"key":"big"}}}
+{"conn":0,"dir":"recv","data":{"Ok":"0123456789012345678901234567890123456789"}}
+{"conn":1,"dir":"send","data":{"Get":{"key":"a"}}}
+{"conn":1,"dir":"recv","data":{"Ok":1}}
+`, client.WithMaxResponseSize(32))
+
+	// The replayer's write of the oversized frame is cut short when the
+	// client drops the connection, so rep.Err is not checked here
+	if _, err := c.Get("big"); !errors.Is(err, client.ErrProtocolError) {
+		t.Fatalf("got %v, want ErrProtocolError", err)
+	}
+	if state := c.State(); state != client.Disconnected {
+		t.Errorf("state = %v, want %v", state, client.Disconnected)
+	}
+
+	value, err := c.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != float64(1) {
+		t.Errorf("got %v, want 1", value)
+	}
+}
+
+func TestStreamPeerCloseDropsConnection(t *testing.T) {
+	c := client.NewLazyClient("closing", client.WithConnFactory(closingDial))
+	defer c.Close()
+
+	if _, _, err := c.QGetStream("doc", "$.items[*]"); err == nil {
+		t.Fatal("expected QGetStream to fail when the server closes")
+	}
+	if state := c.State(); state != client.Disconnected {
+		t.Errorf("state = %v, want %v", state, client.Disconnected)
+	}
+}
+
+func TestReplayAfterClose(t *testing.T) {
+	// No recorded connection: any dial fails with ErrNoMoreConnections
+	c, rep := replay(t, ``)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get("a"); !errors.Is(err, client.ErrClientClosed) {
+		t.Errorf("Get: got %v, want ErrClientClosed", err)
+	}
+	if err := c.SetAsync("a", 1); !errors.Is(err, client.ErrClientClosed) {
+		t.Errorf("SetAsync: got %v, want ErrClientClosed", err)
+	}
+	if _, err := c.Subscribe(context.Background(), "a"); !errors.Is(err, client.ErrClientClosed) {
+		t.Errorf("Subscribe: got %v, want ErrClientClosed", err)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCloseDuringDial(t *testing.T) {
+	dialing := make(chan struct{})
+	release := make(chan struct{})
+	server := make(chan net.Conn, 1)
+
+	c := client.NewLazyClient("slow", client.WithConnFactory(func(ctx context.Context) (net.Conn, error) {
+		close(dialing)
+		<-release
+		clientConn, serverConn := net.Pipe()
+		server <- serverConn
+		return clientConn, nil
+	}))
+
+	done := make(chan error, 1)
+	go func() { done <- c.Ping() }()
+
+	<-dialing
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+
+	if err := <-done; !errors.Is(err, client.ErrClientClosed) {
+		t.Errorf("Ping: got %v, want ErrClientClosed", err)
+	}
+
+	// The connection dialed after Close must not be left open
+	serverConn := <-server
+	serverConn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := serverConn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read on dialed connection: got %v, want EOF", err)
+	}
+}
+
+func TestClientNameRejectedByClose(t *testing.T) {
+	// The first connection closes after reading SetName, as a server that
+	// cannot parse the command does; the client must reconnect without it
+	rep, err := jsonvaulttest.NewReplayer(strings.NewReader(`
+{"conn":0,"dir":"send","data":{"Ping":null}}
+{"conn":0,"dir":"recv","data":"Pong"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dials := 0
+	c := client.NewLazyClient("replay", client.WithClientName("worker"), client.WithConnFactory(func(ctx context.Context) (net.Conn, error) {
+		dials++
+		if dials > 1 {
+			return rep.Dial(ctx)
+		}
+		return closingDial(ctx)
+	}))
+	defer c.Close()
+
+	if err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if dials != 2 {
+		t.Errorf("dialed %d times, want 2", dials)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDebugWriterConcurrentUse(t *testing.T) {
+	// The SetAsync drain writes received frames while commands are sent:
+	// run with -race
+	var debug bytes.Buffer
+	c := client.NewLazyClient("ack", client.WithConnFactory(ackDial), client.WithDebugWriter(&debug))
+	defer c.Close()
+
+	for i := 0; i < 20; i++ {
+		if err := c.SetAsync("a", i); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRejectNilValues(t *testing.T) {
+	// No recorded connection: a nil value must be rejected before dialing
+	c, rep := replay(t, ``, client.WithRejectNilValues())
+	async := c.Async(1)
+	defer async.Close()
+
+	var nilMap map[string]interface{}
+	if err := c.SetAsync("a", nil); !errors.Is(err, client.ErrNilValue) {
+		t.Errorf("SetAsync: got %v, want ErrNilValue", err)
+	}
+	// The queue snapshots values, so nil must be caught before encoding
+	if err := async.Set("a", nilMap); !errors.Is(err, client.ErrNilValue) {
+		t.Errorf("AsyncClient.Set: got %v, want ErrNilValue", err)
+	}
+
+	p := c.Pipeline()
+	p.Set("a", 1)
+	p.Merge("b", nilMap)
+	if _, err := p.Exec(); !errors.Is(err, client.ErrNilValue) {
+		t.Errorf("Pipeline.Merge: got %v, want ErrNilValue", err)
+	}
+	p.QSet("a", "$.x", nil)
+	if _, err := p.Exec(); !errors.Is(err, client.ErrNilValue) {
+		t.Errorf("Pipeline.QSet: got %v, want ErrNilValue", err)
+	}
+	if p.Len() != 0 {
+		t.Errorf("pipeline still holds %d commands", p.Len())
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPushBeforeStreamedResponse(t *testing.T) {
+	var pushed []interface{}
+	c, rep := replay(t, `
+{"conn":0,"dir":"send","data":{"QGet":{"key":"doc","query":"$.items[*]"}}}
+{"conn":0,"dir":"recv","data":{"Push":"notice"}}
+{"conn":0,"dir":"recv","data":{"Ok":[1,2]}}
+`, client.WithPushHandler(func(msg interface{}) { pushed = append(pushed, msg) }))
+
+	var items []interface{}
+	if err := c.QGetEach("doc", "$.items[*]", func(item interface{}) error {
+		items = append(items, item)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Errorf("got %v, want 2 items", items)
+	}
+	if len(pushed) != 1 || pushed[0] != "notice" {
+		t.Errorf("pushed %v, want [notice]", pushed)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPushOnSubscription(t *testing.T) {
+	pushed := make(chan interface{}, 1)
+	c, rep := replay(t, `
+{"conn":0,"dir":"send","data":{"Subscribe":{"key":"a"}}}
+{"conn":0,"dir":"recv","data":{"Ok":null}}
+{"conn":0,"dir":"recv","data":{"Push":"notice"}}
+{"conn":0,"dir":"recv","data":{"Event":{"key":"a","op":"Set","value":1}}}
+`, client.WithPushHandler(func(msg interface{}) { pushed <- msg }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := c.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, ok := <-events
+	if !ok {
+		t.Fatal("event stream ended at the push frame")
+	}
+	if event.Key != "a" || event.Op != "Set" {
+		t.Errorf("got %+v", event)
+	}
+	if msg := <-pushed; msg != "notice" {
+		t.Errorf("pushed %v, want notice", msg)
+	}
+	if err := rep.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQGetEachDecodeErrorIsOperationError(t *testing.T) {
+	// A malformed element cannot be recorded, so the server is handmade
+	c := client.NewLazyClient("malformed", client.WithConnFactory(func(ctx context.Context) (net.Conn, error) {
+		clientConn, serverConn := net.Pipe()
+		go func() {
+			defer serverConn.Close()
+			var prefix [4]byte
+			if _, err := io.ReadFull(serverConn, prefix[:]); err != nil {
+				return
+			}
+			io.CopyN(io.Discard, serverConn, int64(client.DefaultByteOrder.Uint32(prefix[:])))
+
+			resp := []byte(`{"Ok":[1,}]}`)
+			client.DefaultByteOrder.PutUint32(prefix[:], uint32(len(resp)))
+			serverConn.Write(append(prefix[:], resp...))
+		}()
+		return clientConn, nil
+	}))
+	defer c.Close()
+
+	err := c.QGetEach("doc", "$.items[*]", func(item interface{}) error { return nil })
+	var opErr *client.OperationError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("got %v, want an OperationError", err)
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+)
+
+// retryPolicy configures automatic command retries
+type retryPolicy struct {
+	attempts int
+	backoff  Backoff
+}
+
+// reconnectPolicy configures automatic reconnection
+type reconnectPolicy struct {
+	attempts int
+	backoff  Backoff
+}
+
+// invokeWithRetry calls invoke, retrying transport failures when it is safe:
+// always if the command was not completely written, and for idempotent
+// commands otherwise. Idempotent commands rejected with a busy response are
+// retried too, giving the server time to recover.
+func (c *Client) invokeWithRetry(ctx context.Context, cmd interface{}) (interface{}, error) {
+	resp, err := c.invoke(ctx, cmd)
+	if c.retry == nil {
+		return resp, err
+	}
+
+	for attempt := 1; attempt < c.retry.attempts && retryable(cmd, resp, err); attempt++ {
+		if err := sleepContext(ctx, c.retry.backoff.NextDelay(attempt)); err != nil {
+			return nil, err
+		}
+		resp, err = c.invoke(ctx, cmd)
+	}
+	return resp, err
+}
+
+// retryable reports whether a command that failed, or got a busy response,
+// can be sent again safely
+func retryable(cmd, resp interface{}, err error) bool {
+	if err == nil {
+		return serverBusy(resp) && idempotent(cmd)
+	}
+
+	var terr *TransportError
+	if !errors.As(err, &terr) {
+		return false
+	}
+	return !terr.WroteFully || idempotent(cmd)
+}
+
+// dialWithReconnect dials the server, retrying according to the reconnect
+// policy when one is configured
+func (c *Client) dialWithReconnect(ctx context.Context) error {
+	err := c.connect(ctx)
+	if c.reconnect == nil {
+		return err
+	}
+
+	for attempt := 1; err != nil && attempt < c.reconnect.attempts; attempt++ {
+		// Report the backoff wait as part of the reconnection
+		c.setState(Reconnecting)
+		if err := sleepContext(ctx, c.reconnect.backoff.NextDelay(attempt)); err != nil {
+			c.setState(Disconnected)
+			return err
+		}
+		err = c.connect(ctx)
+	}
+	return err
+}
+
+// dropConn closes a connection left in an unknown state so that the next
+// command dials a fresh one. The caller must hold c.mu.
+func (c *Client) dropConn() {
+	if c.conn == nil {
+		return
+	}
+	c.conn.Close()
+	c.conn = nil
+	c.setLive(nil)
+	c.reader = nil
+	c.demux = nil
+	c.setState(Disconnected)
+}
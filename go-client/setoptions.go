@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetOption configures a single Set call
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	data     *SetData
+	previous *interface{}
+}
+
+// WithTTL makes the key expire after ttl. A server without TTL support
+// would store the key without expiration, so the Set fails with
+// ErrUnsupportedCapability unless Capabilities reported TTL support.
+func WithTTL(ttl time.Duration) SetOption {
+	return func(o *setOptions) {
+		o.data.TTLMs = ttl.Milliseconds()
+	}
+}
+
+// OnlyIfAbsent sets the value only if the key does not exist yet.
+// It overrides OnlyIfExists.
+func OnlyIfAbsent() SetOption {
+	return func(o *setOptions) {
+		o.data.Condition = "absent"
+	}
+}
+
+// OnlyIfExists sets the value only if the key already exists.
+// It overrides OnlyIfAbsent.
+func OnlyIfExists() SetOption {
+	return func(o *setOptions) {
+		o.data.Condition = "exists"
+	}
+}
+
+// DurabilityLevel is the guarantee the server gives before acknowledging a write
+type DurabilityLevel string
+
+const (
+	// Async acknowledges once the write is applied in memory, persisting it
+	// in the background
+	Async DurabilityLevel = "async"
+	// Synced acknowledges once the write is flushed to disk
+	Synced DurabilityLevel = "synced"
+	// Replicated acknowledges once the write reached the replicas
+	Replicated DurabilityLevel = "replicated"
+)
+
+// WithDurability sets the durability the server must reach before
+// acknowledging the write. Without it the server default applies. The
+// acknowledgment must echo the level: a server ignoring the field answers
+// with a plain acknowledgment, and the Set returns an error, although the
+// value was written with the server default durability.
+func WithDurability(level DurabilityLevel) SetOption {
+	return func(o *setOptions) {
+		o.data.Durability = level
+	}
+}
+
+// ReturnPrevious stores in prev the value the key held before the Set,
+// nil if it did not exist
+func ReturnPrevious(prev *interface{}) SetOption {
+	return func(o *setOptions) {
+		o.data.ReturnPrevious = true
+		o.previous = prev
+	}
+}
+
+// apply interprets the {"applied": bool, "previous": any} result returned
+// for a Set with options. A conditional or ReturnPrevious Set requires that
+// result: a plain acknowledgment means the server ignored the option, and
+// wrote the value unconditionally. Likewise a Set with WithDurability
+// requires the result to echo the level as "durability".
+func (o *setOptions) apply(cmd interface{}, result interface{}) error {
+	m, ok := result.(map[string]interface{})
+	if level := o.data.Durability; level != "" && m["durability"] != string(level) {
+		return wrapOperationError(cmd, fmt.Errorf("server did not acknowledge durability %q, got %v", level, result))
+	}
+
+	applied, hasApplied := m["applied"].(bool)
+	if !ok || !hasApplied {
+		if o.data.Condition != "" || o.data.ReturnPrevious {
+			return wrapOperationError(cmd, fmt.Errorf("server ignored the set options, got %v", result))
+		}
+		// Plain acknowledgment
+		return nil
+	}
+
+	if o.previous != nil {
+		*o.previous = m["previous"]
+	}
+	if !applied {
+		return wrapOperationError(cmd, ErrConditionNotMet)
+	}
+	return nil
+}
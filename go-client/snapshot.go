@@ -0,0 +1,155 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SnapshotCommand represents a SNAPSHOT command
+type SnapshotCommand struct {
+	Snapshot interface{} `json:"Snapshot"`
+}
+
+// SnapshotExportCommand represents a SNAPSHOTEXPORT command
+type SnapshotExportCommand struct {
+	SnapshotExport SnapshotExportData `json:"SnapshotExport"`
+}
+
+type SnapshotExportData struct {
+	ID string `json:"id"`
+}
+
+// Snapshot asks the server to create a consistent point-in-time snapshot of
+// the whole database and returns its ID, for SnapshotExport
+func (c *Client) Snapshot(ctx context.Context) (string, error) {
+	cmd := SnapshotCommand{
+		Snapshot: nil,
+	}
+
+	value, err := c.callContext(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+
+	id, ok := value.(string)
+	if !ok {
+		return "", wrapOperationError(cmd, fmt.Errorf("unexpected value type: %T", value))
+	}
+	return id, nil
+}
+
+// SnapshotExport streams the snapshot with the given ID to w as JSON,
+// without buffering it in memory. The snapshot is sent in a single response
+// frame, so WithMaxResponseSize must allow its size.
+//
+// Streamed commands bypass the interceptor chain.
+func (c *Client) SnapshotExport(id string, w io.Writer) error {
+	cmd := SnapshotExportCommand{
+		SnapshotExport: SnapshotExportData{
+			ID: id,
+		},
+	}
+
+	body, err := c.openStream(cmd)
+	if err != nil {
+		return wrapOperationError(cmd, err)
+	}
+	defer c.closeStream(body)
+
+	decoder := json.NewDecoder(body)
+	if err := enterOkValue(decoder); err != nil {
+		return wrapOperationError(cmd, err)
+	}
+
+	// Copy the raw value, leaving out the separator after the "Ok" key,
+	// which the decoder has not consumed yet, and the closing brace of the
+	// envelope
+	value := bufio.NewReader(io.MultiReader(decoder.Buffered(), body))
+	if err := skipSeparator(value); err != nil {
+		return wrapOperationError(cmd, err)
+	}
+	out := &envelopeTrimmer{w: w}
+	if _, err := io.Copy(out, value); err != nil {
+		return wrapOperationError(cmd, err)
+	}
+	if err := out.finish(); err != nil {
+		return wrapOperationError(cmd, err)
+	}
+	return nil
+}
+
+// skipSeparator advances r past whitespace and the colon preceding a value
+func skipSeparator(r *bufio.Reader) error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("failed to read stream response: %w", err)
+		}
+		if b != ':' && !isJSONSpace(b) {
+			return r.UnreadByte()
+		}
+	}
+}
+
+// envelopeTrimmer passes a JSON value through to w, holding back the last
+// closing brace and any whitespace after it, which belong to the enclosing
+// response object
+type envelopeTrimmer struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (t *envelopeTrimmer) Write(p []byte) (int, error) {
+	start := 0
+	for i, b := range p {
+		switch {
+		case b == '}':
+			// Release what was held back and hold this brace instead
+			if err := t.release(p[start:i]); err != nil {
+				return 0, err
+			}
+			t.pending = append(t.pending, b)
+			start = i + 1
+		case len(t.pending) > 0 && start == i && isJSONSpace(b):
+			t.pending = append(t.pending, b)
+			start = i + 1
+		}
+	}
+
+	if start < len(p) {
+		if err := t.release(p[start:]); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// release writes the held back bytes followed by data
+func (t *envelopeTrimmer) release(data []byte) error {
+	if len(t.pending) > 0 {
+		if _, err := t.w.Write(t.pending); err != nil {
+			return err
+		}
+		t.pending = t.pending[:0]
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	_, err := t.w.Write(data)
+	return err
+}
+
+// finish checks that the held back bytes close the envelope
+func (t *envelopeTrimmer) finish() error {
+	if len(t.pending) == 0 {
+		return fmt.Errorf("unterminated response")
+	}
+	return nil
+}
+
+func isJSONSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEnvelopeTrimmer(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"object", `{"a":1}}`, `{"a":1}`},
+		{"nested braces", `{"a":{"b":{}}}}`, `{"a":{"b":{}}}`},
+		{"trailing whitespace", "{\"a\":[1, 2]} \n}\n", "{\"a\":[1, 2]} \n"},
+		{"scalar", `42}`, `42`},
+		{"brace in string", `{"s":"}"}}`, `{"s":"}"}`},
+	}
+
+	for _, tt := range tests {
+		// Splitting the input at every offset covers braces and whitespace
+		// held back across writes
+		for split := 0; split <= len(tt.input); split++ {
+			var out bytes.Buffer
+			trimmer := &envelopeTrimmer{w: &out}
+			if _, err := trimmer.Write([]byte(tt.input[:split])); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if _, err := trimmer.Write([]byte(tt.input[split:])); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if err := trimmer.finish(); err != nil {
+				t.Fatalf("%s, split at %d: %v", tt.name, split, err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("%s, split at %d: got %q, want %q", tt.name, split, out.String(), tt.want)
+			}
+		}
+	}
+}
+
+func TestEnvelopeTrimmerUnterminated(t *testing.T) {
+	var out bytes.Buffer
+	trimmer := &envelopeTrimmer{w: &out}
+	trimmer.Write([]byte(`[1, 2`))
+	if err := trimmer.finish(); err == nil {
+		t.Fatal("expected an error for a response without a closing brace")
+	}
+}
+
+func TestSkipSeparator(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(" : \n{\"a\":1}"))
+	if err := skipSeparator(r); err != nil {
+		t.Fatal(err)
+	}
+	rest, _ := r.ReadString(0)
+	if rest != `{"a":1}` {
+		t.Errorf("got %q after the separator", rest)
+	}
+}
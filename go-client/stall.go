@@ -0,0 +1,73 @@
+package client
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// stallConn enforces WithMaxReadStall: every Read must receive data within
+// stall, on top of any deadline set by the client. Connections waiting for
+// pushed data, such as subscriptions, disable the check as they may stay
+// idle indefinitely.
+type stallConn struct {
+	net.Conn
+	stall time.Duration
+
+	mu       sync.Mutex
+	deadline time.Time // read deadline set by the client
+	disabled bool
+}
+
+func (s *stallConn) SetDeadline(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadline = t
+	return s.Conn.SetDeadline(t)
+}
+
+func (s *stallConn) SetReadDeadline(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadline = t
+	return s.Conn.SetReadDeadline(t)
+}
+
+// Read refreshes the rolling deadline before reading, so only a read that
+// gets no data at all for the stall duration times out
+func (s *stallConn) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	if !s.disabled {
+		deadline := time.Now().Add(s.stall)
+		if !s.deadline.IsZero() && s.deadline.Before(deadline) {
+			deadline = s.deadline
+		}
+		if err := s.Conn.SetReadDeadline(deadline); err != nil {
+			s.mu.Unlock()
+			return 0, err
+		}
+	}
+	s.mu.Unlock()
+
+	return s.Conn.Read(p)
+}
+
+// disable stops enforcing the stall timeout, restoring the deadline set by
+// the client
+func (s *stallConn) disable() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.disabled = true
+	s.Conn.SetReadDeadline(s.deadline)
+}
+
+// allowIdleReads disables the read stall timeout on the connection, for
+// connections that wait for pushed data
+func (c *Client) allowIdleReads() {
+	if s, ok := c.conn.(*stallConn); ok {
+		s.disable()
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import "fmt"
+
+// ConnState is the state of the client connection
+type ConnState int32
+
+const (
+	// Disconnected means no connection is open, either not dialed yet or lost
+	Disconnected ConnState = iota
+	// Connecting means the first dial is in progress
+	Connecting
+	// Connected means the connection is open
+	Connected
+	// Reconnecting means a new dial is in progress after a lost connection
+	Reconnecting
+	// Closed means the client has been closed
+	Closed
+)
+
+var connStateNames = [...]string{
+	Disconnected: "Disconnected",
+	Connecting:   "Connecting",
+	Connected:    "Connected",
+	Reconnecting: "Reconnecting",
+	Closed:       "Closed",
+}
+
+// String returns the name of the state
+func (s ConnState) String() string {
+	if s >= 0 && int(s) < len(connStateNames) {
+		return connStateNames[s]
+	}
+	return fmt.Sprintf("ConnState(%d)", int32(s))
+}
+
+// setState records a state transition and notifies the listener.
+// Once closed, the client stays closed.
+func (c *Client) setState(state ConnState) {
+	for {
+		old := c.State()
+		if old == state || old == Closed {
+			return
+		}
+		if c.state.CompareAndSwap(int32(old), int32(state)) {
+			if c.stateListener != nil {
+				c.stateListener(old, state)
+			}
+			return
+		}
+	}
+}
+
+// State returns the current connection state without doing any I/O, e.g.
+// for readiness checks. A client waiting to redial after a failure reports
+// Reconnecting.
+func (c *Client) State() ConnState {
+	return ConnState(c.state.Load())
+}
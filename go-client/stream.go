@@ -0,0 +1,385 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QGetStream executes a JSONPath query and returns a decoder positioned
+// inside the resulting array, so elements can be read one at a time with
+// decoder.More and decoder.Decode. The returned close function must be
+// called once done: it discards any unread part of the response and releases
+// the connection, which other commands wait for in the meantime.
+//
+// Streamed commands bypass the interceptor chain.
+func (c *Client) QGetStream(key, query string) (*json.Decoder, func() error, error) {
+	cmd := QGetCommand{
+		QGet: QGetData{
+			Key:   c.key(key),
+			Query: query,
+		},
+	}
+
+	body, err := c.openStream(cmd)
+	if err != nil {
+		return nil, nil, wrapOperationError(cmd, err)
+	}
+	var once sync.Once
+	closeFn := func() error {
+		var err error
+		once.Do(func() {
+			err = c.closeStream(body)
+		})
+		return err
+	}
+
+	decoder := json.NewDecoder(body)
+	if err := enterOkValue(decoder); err != nil {
+		closeFn()
+		return nil, nil, wrapOperationError(cmd, err)
+	}
+
+	decoder, err = enterArray(decoder)
+	if err != nil {
+		closeFn()
+		return nil, nil, wrapOperationError(cmd, err)
+	}
+	return decoder, closeFn, nil
+}
+
+// ScanEach calls fn for each key matching the glob pattern as it is decoded
+// from the wire, without buffering the whole key list. Iteration stops at
+// the first error returned by fn, or when ctx is done: the connection is
+// then dropped rather than drained.
+func (c *Client) ScanEach(ctx context.Context, pattern string, fn func(key string) error) error {
+	cmd := ScanCommand{
+		Scan: ScanData{
+			Pattern: c.key(pattern),
+		},
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	body, err := c.openStream(cmd)
+	if err != nil {
+		return wrapOperationError(cmd, err)
+	}
+
+	// Interrupt a blocked read when ctx is done
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer func() {
+		if stop() {
+			c.closeStream(body)
+			return
+		}
+		// The deadline may have cut the response: never reuse the connection
+		c.dropConn()
+		c.mu.Unlock()
+	}()
+
+	decoder := json.NewDecoder(body)
+	if err := enterOkValue(decoder); err != nil {
+		return scanError(ctx, cmd, err)
+	}
+	decoder, err = enterArray(decoder)
+	if err != nil {
+		return scanError(ctx, cmd, err)
+	}
+
+	for decoder.More() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var key string
+		if err := decoder.Decode(&key); err != nil {
+			return scanError(ctx, cmd, fmt.Errorf("failed to decode stream element: %w", err))
+		}
+		if err := fn(c.unkey(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanError reports a failed streamed read, which is the context error if
+// ctx interrupted it
+func scanError(ctx context.Context, cmd interface{}, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return wrapOperationError(cmd, err)
+}
+
+// enterArray advances the decoder past the opening of an array value. A
+// null value yields a decoder with no elements.
+func enterArray(decoder *json.Decoder) (*json.Decoder, error) {
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream response: %w", err)
+	}
+	switch tok {
+	case json.Delim('['):
+		return decoder, nil
+	case nil:
+		// No matches: hand out a decoder with no elements
+		return json.NewDecoder(strings.NewReader("")), nil
+	default:
+		return nil, fmt.Errorf("unexpected stream value: %v", tok)
+	}
+}
+
+// openStream sends a command and returns a reader limited to the response
+// body. The connection stays locked until closeStream is called.
+func (c *Client) openStream(cmd interface{}) (*io.LimitedReader, error) {
+	data, err := c.encodeCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	body, err := c.startStream(data)
+	if err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	return body, nil
+}
+
+// startStream writes the command frame and reads the response length,
+// skipping push frames and dropping the connection if either fails. The
+// caller must hold c.mu.
+func (c *Client) startStream(data []byte) (*io.LimitedReader, error) {
+	if c.multiplexed {
+		return nil, ErrMultiplexed
+	}
+
+	if err := c.dialWithReconnect(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if err := c.writeFrame(data); err != nil {
+		c.dropConn()
+		return nil, err
+	}
+
+	// Push frames may precede the response: hand them off before
+	// streaming the response body
+	for {
+		length, err := c.readFrameLength()
+		if err != nil {
+			c.dropConn()
+			return nil, err
+		}
+
+		pushed, err := c.skipPush(length)
+		if err != nil {
+			c.dropConn()
+			return nil, err
+		}
+		if !pushed {
+			return &io.LimitedReader{R: c.reader, N: int64(length)}, nil
+		}
+	}
+}
+
+// pushPrefix starts every push frame, as compactly encoded by the server
+var pushPrefix = []byte(`{"Push":`)
+
+// skipPush reads the next frame, of the given length, if it is a push frame,
+// hands it to the push handler and reports whether it did. Any other frame
+// is left unread. The caller must hold c.mu.
+func (c *Client) skipPush(length uint32) (bool, error) {
+	if length < uint32(len(pushPrefix)) {
+		return false, nil
+	}
+	prefix, err := c.reader.Peek(len(pushPrefix))
+	if err != nil {
+		return false, fmt.Errorf("failed to read response data: %w", err)
+	}
+	if !bytes.Equal(prefix, pushPrefix) {
+		return false, nil
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		return false, fmt.Errorf("failed to read response data: %w", err)
+	}
+	c.debugFrame("<--", data)
+
+	if !c.handlePush(data) {
+		return false, fmt.Errorf("unexpected response: %s", data)
+	}
+	return true, nil
+}
+
+// closeStream discards the unread part of a streamed response and unlocks
+// the connection. If the response cannot be drained the connection is
+// dropped, as it is left mid-frame.
+func (c *Client) closeStream(body *io.LimitedReader) error {
+	defer c.mu.Unlock()
+
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		c.dropConn()
+		return fmt.Errorf("failed to drain response data: %w", err)
+	}
+	return nil
+}
+
+// enterOkValue advances the decoder past the opening of an {"Ok": ...}
+// response, leaving it positioned at the value. Error responses are
+// decoded and returned as errors.
+func enterOkValue(decoder *json.Decoder) error {
+	tok, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read stream response: %w", err)
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("unexpected response type: %v", tok)
+	}
+
+	tok, err = decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read stream response: %w", err)
+	}
+
+	switch tok {
+	case "Ok":
+		return nil
+	case "Error":
+		var msg interface{}
+		if err := decoder.Decode(&msg); err != nil {
+			return fmt.Errorf("failed to read stream response: %w", err)
+		}
+		_, err := parseResponse(map[string]interface{}{"Error": msg})
+		return err
+	default:
+		return errors.New("unknown response format")
+	}
+}
+
+// QGetEach executes a JSONPath query and calls fn for each matched element as
+// it is decoded from the wire, keeping memory flat regardless of the result
+// size. Iteration stops at the first error returned by fn.
+func (c *Client) QGetEach(key, query string, fn func(item interface{}) error) error {
+	decoder, closeFn, err := c.QGetStream(key, query)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	for decoder.More() {
+		var item interface{}
+		if err := decoder.Decode(&item); err != nil {
+			cmd := QGetCommand{QGet: QGetData{Key: c.key(key), Query: query}}
+			return wrapOperationError(cmd, fmt.Errorf("failed to decode stream element: %w", err))
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStream stores size bytes read from r as the value of the given key,
+// copying them straight to the connection instead of buffering the document.
+// r must hold exactly one JSON value of exactly size bytes: the frame length
+// is computed from size up front, and the value is not validated client-side.
+// If r ends early the connection is dropped, as it is left mid-frame.
+//
+// Streamed commands bypass the interceptor chain.
+func (c *Client) SetStream(key string, r io.Reader, size int64) error {
+	cmd := SetCommand{Set: SetData{Key: c.key(key)}}
+
+	head, tail, err := c.splitValue(cmd)
+	if err != nil {
+		return wrapOperationError(cmd, err)
+	}
+
+	c.mu.Lock()
+	resp, err := c.streamValue(head, r, size, tail)
+	c.mu.Unlock()
+	if err != nil {
+		return wrapOperationError(cmd, err)
+	}
+
+	if _, err := parseResponse(resp); err != nil {
+		return wrapOperationError(cmd, classifyServerError(cmd, err))
+	}
+	return nil
+}
+
+// splitValue encodes a command whose value is null and returns the bytes
+// around the value, between which a streamed value is written
+func (c *Client) splitValue(cmd interface{}) (head, tail []byte, err error) {
+	data, err := c.encodeCommand(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The value is the last field written, so its null is the last one
+	i := bytes.LastIndex(data, []byte("null"))
+	if i < 0 {
+		return nil, nil, errors.New("failed to locate value in command")
+	}
+	return data[:i], data[i+len("null"):], nil
+}
+
+// streamValue writes a frame made of head, size bytes from r and tail, then
+// reads the response. The caller must hold c.mu.
+func (c *Client) streamValue(head []byte, r io.Reader, size int64, tail []byte) (interface{}, error) {
+	if c.multiplexed {
+		return nil, ErrMultiplexed
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("invalid size: %d", size)
+	}
+
+	length := uint64(len(head)) + uint64(size) + uint64(len(tail))
+	if length > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrValueTooLarge, length)
+	}
+
+	if err := c.dialWithReconnect(context.Background()); err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, 4, 4+len(head))
+	c.byteOrder.PutUint32(prefix, uint32(length))
+	if _, err := c.conn.Write(append(prefix, head...)); err != nil {
+		c.dropConn()
+		return nil, &TransportError{WroteFully: false, Err: fmt.Errorf("failed to write data: %w", err)}
+	}
+
+	if n, err := io.CopyN(c.conn, r, size); err != nil {
+		c.dropConn()
+		if err == io.EOF {
+			err = fmt.Errorf("value ended after %d of %d bytes: %w", n, size, io.ErrUnexpectedEOF)
+		}
+		return nil, &TransportError{WroteFully: false, Err: err}
+	}
+
+	if _, err := c.conn.Write(tail); err != nil {
+		c.dropConn()
+		return nil, &TransportError{WroteFully: false, Err: fmt.Errorf("failed to write data: %w", err)}
+	}
+
+	resp, err := c.readResponse()
+	if err != nil {
+		c.dropConn()
+		return nil, &TransportError{WroteFully: true, Err: err}
+	}
+	return resp, nil
+}
@@ -0,0 +1,30 @@
+package client
+
+import "testing"
+
+func TestSplitValue(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"externally tagged", nil, `{"Set":{"key":"null","value":[1]}}`},
+		{"internally tagged", []Option{WithEncoder(InternallyTagged{})}, `{"type":"Set","key":"null","value":[1]}`},
+		{"key prefix", []Option{WithKeyPrefix("null:")}, `{"Set":{"key":"null:null","value":[1]}}`},
+	}
+
+	for _, tt := range tests {
+		c := newClient("", tt.opts)
+		// A key containing null must not be mistaken for the value
+		cmd := SetCommand{Set: SetData{Key: c.key("null")}}
+
+		head, tail, err := c.splitValue(cmd)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := string(head) + "[1]" + string(tail); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetAndSubscribeCommand represents a GETANDSUBSCRIBE command
+type GetAndSubscribeCommand struct {
+	GetAndSubscribe GetAndSubscribeData `json:"GetAndSubscribe"`
+}
+
+type GetAndSubscribeData struct {
+	Key string   `json:"key"`
+	Ops []string `json:"ops,omitempty"`
+}
+
+// SubscribeCommand represents a SUBSCRIBE command
+type SubscribeCommand struct {
+	Subscribe SubscribeData `json:"Subscribe"`
+}
+
+type SubscribeData struct {
+	Key string   `json:"key"`
+	Ops []string `json:"ops,omitempty"`
+}
+
+// SubscribeAllCommand represents a SUBSCRIBEALL command
+type SubscribeAllCommand struct {
+	SubscribeAll interface{} `json:"SubscribeAll"`
+}
+
+// SubscribeOption configures a subscription
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	ops []string
+}
+
+// WithOpFilter asks the server to send only the events of the given
+// operation types, e.g. "Delete"
+func WithOpFilter(ops ...string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.ops = append(o.ops, ops...)
+	}
+}
+
+// newSubscribeOptions applies subscription options
+func newSubscribeOptions(opts []SubscribeOption) subscribeOptions {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// KeyEvent describes a change applied to a key. Seq is the server sequence
+// number of the change, increasing by one for every mutation, so that a gap
+// reveals missed events; it is zero if the server does not send it. Time is
+// when the server applied the change, zero if not sent.
+type KeyEvent struct {
+	Key   string      `json:"key"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+	Seq   uint64      `json:"seq,omitempty"`
+	Time  time.Time   `json:"time"`
+}
+
+// UnmarshalJSON decodes an event, accepting RFC 3339 or epoch milliseconds
+// timestamps
+func (e *KeyEvent) UnmarshalJSON(data []byte) error {
+	type keyEvent KeyEvent
+	aux := struct {
+		*keyEvent
+		Time *Timestamp `json:"time"`
+	}{keyEvent: (*keyEvent)(e)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Time != nil {
+		e.Time = aux.Time.Time
+	}
+	return nil
+}
+
+// eventFrame is the envelope of an event pushed by the server
+type eventFrame struct {
+	Event *KeyEvent `json:"Event"`
+}
+
+// GetAndSubscribe returns the current value of key together with a stream of
+// the changes applied after it. The server reads the value and registers the
+// subscription atomically, so no update can be missed in between.
+//
+// The subscription uses a dedicated connection which is closed, together with
+// the events channel, when ctx is done or the connection fails.
+func (c *Client) GetAndSubscribe(ctx context.Context, key string, opts ...SubscribeOption) (interface{}, <-chan KeyEvent, error) {
+	o := newSubscribeOptions(opts)
+	cmd := GetAndSubscribeCommand{
+		GetAndSubscribe: GetAndSubscribeData{
+			Key: c.key(key),
+			Ops: o.ops,
+		},
+	}
+
+	sub, initial, err := c.subscribe(ctx, cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return initial, sub.events(ctx), nil
+}
+
+// Subscribe returns a stream of the changes applied to key. It runs on a
+// dedicated connection which is closed, together with the events channel,
+// when ctx is done or the connection fails.
+func (c *Client) Subscribe(ctx context.Context, key string, opts ...SubscribeOption) (<-chan KeyEvent, error) {
+	o := newSubscribeOptions(opts)
+	cmd := SubscribeCommand{
+		Subscribe: SubscribeData{
+			Key: c.key(key),
+			Ops: o.ops,
+		},
+	}
+
+	sub, _, err := c.subscribe(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub.events(ctx), nil
+}
+
+// SubscribeAll returns a stream of every change applied to the database,
+// for change data capture. Events carry sequence numbers: a gap in Seq,
+// e.g. after resubscribing, means events were missed. With WithKeyPrefix
+// only the events of keys in the namespace are delivered. It runs on a
+// dedicated connection which is closed, together with the events channel,
+// when ctx is done or the connection fails.
+func (c *Client) SubscribeAll(ctx context.Context) (<-chan KeyEvent, error) {
+	cmd := SubscribeAllCommand{
+		SubscribeAll: nil,
+	}
+
+	sub, _, err := c.subscribe(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub.events(ctx), nil
+}
+
+// subscribe opens a dedicated connection, sends the subscription command and
+// returns the connection client together with the initial response value
+func (c *Client) subscribe(ctx context.Context, cmd interface{}) (*Client, interface{}, error) {
+	if c.State() == Closed {
+		return nil, nil, wrapOperationError(cmd, ErrClientClosed)
+	}
+
+	c.mu.Lock()
+	err := c.checkCapability(cmd)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, nil, wrapOperationError(cmd, err)
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, nil, wrapOperationError(cmd, err)
+	}
+	sub := c.derive(conn)
+
+	initial, err := sub.callContext(ctx, cmd)
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	return sub, initial, nil
+}
+
+// events reads pushed event frames until ctx is done or the connection fails
+func (c *Client) events(ctx context.Context) <-chan KeyEvent {
+	ch := make(chan KeyEvent)
+
+	// Events may be arbitrarily far apart
+	c.allowIdleReads()
+
+	// Unblock the reader when the context is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		c.Close()
+	})
+
+	go func() {
+		defer close(ch)
+		defer stop()
+		defer c.Close()
+
+		for {
+			event, err := c.readEvent()
+			if err != nil {
+				return
+			}
+
+			select {
+			case ch <- *event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// readEvent reads the next event frame from the connection, skipping the
+// events of keys outside the key prefix and handing push frames to the push
+// handler
+func (c *Client) readEvent() (*KeyEvent, error) {
+	for {
+		data, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		var frame eventFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		if frame.Event == nil {
+			if c.handlePush(data) {
+				continue
+			}
+			return nil, fmt.Errorf("unexpected event frame: %s", data)
+		}
+		if !strings.HasPrefix(frame.Event.Key, c.keyPrefix) {
+			continue
+		}
+		frame.Event.Key = c.unkey(frame.Event.Key)
+		return frame.Event, nil
+	}
+}
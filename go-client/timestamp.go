@@ -0,0 +1,48 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Timestamp is a time.Time decoded from either an RFC 3339 string or a
+// number of milliseconds since the Unix epoch, the two forms servers use.
+// It can be used in the structs passed to GetInto. It encodes as RFC 3339.
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	parsed, err := parseTimestamp(value)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// parseTimestamp converts a decoded JSON timestamp to a time.Time. null is
+// the zero time.
+func parseTimestamp(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case float64:
+		return time.UnixMilli(int64(v)), nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp: %w", err)
+		}
+		return parsed, nil
+	default:
+		return time.Time{}, fmt.Errorf("unexpected timestamp type: %T", value)
+	}
+}
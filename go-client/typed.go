@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// GetSlice retrieves the array stored at the given key decoded into a typed
+// slice. found is false when the key does not exist.
+func GetSlice[T any](c *Client, key string) ([]T, bool, error) {
+	value, found, err := c.GetWithFound(key)
+	if err != nil || !found {
+		return nil, found, err
+	}
+
+	var result []T
+	if err := decodeValue(key, value, &result); err != nil {
+		return nil, true, err
+	}
+	return result, true, nil
+}
+
+// MGetInto retrieves several keys in a single round trip, decoding each value
+// into T. Missing keys are absent from the result map. The reads are
+// pipelined, so they bypass the interceptor chain.
+func MGetInto[T any](c *Client, keys []string) (map[string]T, error) {
+	results, err := c.getMany(context.Background(), keys)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMany[T](keys, results)
+}
+
+// MGetIntoPartial is like MGetInto, but when ctx is done before every value
+// is read it returns the values read so far together with the keys that
+// timed out, instead of failing. It suits latency budgets where a partial
+// answer beats waiting for all keys.
+func MGetIntoPartial[T any](ctx context.Context, c *Client, keys []string) (map[string]T, []string, error) {
+	results, err := c.getMany(ctx, keys)
+	if err != nil && ctx.Err() == nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil, nil, err
+	}
+
+	values, err := decodeMany[T](keys[:len(results)], results)
+	if err != nil {
+		return nil, nil, err
+	}
+	return values, keys[len(results):], nil
+}
+
+// decodeMany decodes the GETWITHFOUND results of keys into a map, skipping
+// missing keys
+func decodeMany[T any](keys []string, results []PipelineResult) (map[string]T, error) {
+	values := make(map[string]T, len(keys))
+	for i, result := range results {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		value, found, err := toFoundValue(result.Value)
+		if err != nil {
+			cmd := GetWithFoundCommand{GetWithFound: GetData{Key: keys[i]}}
+			return nil, wrapOperationError(cmd, err)
+		}
+		if !found {
+			continue
+		}
+
+		var v T
+		if err := decodeValue(keys[i], value, &v); err != nil {
+			return nil, err
+		}
+		values[keys[i]] = v
+	}
+	return values, nil
+}
+
+// getMany sends a GETWITHFOUND for each key, pipelined unless the client is
+// multiplexed, and returns the raw results in order. When ctx is done it
+// also returns the results read until then.
+func (c *Client) getMany(ctx context.Context, keys []string) ([]PipelineResult, error) {
+	cmds := make([]interface{}, len(keys))
+	for i, key := range keys {
+		cmds[i] = GetWithFoundCommand{
+			GetWithFound: GetData{
+				Key: c.key(key),
+			},
+		}
+	}
+
+	if c.multiplexed {
+		results := make([]PipelineResult, 0, len(cmds))
+		for _, cmd := range cmds {
+			value, err := c.callContext(ctx, cmd)
+			if err != nil && ctx.Err() != nil {
+				return results, err
+			}
+			results = append(results, PipelineResult{Value: value, Err: err})
+		}
+		return results, nil
+	}
+
+	p := c.Pipeline()
+	p.cmds = cmds
+	return p.exec(ctx)
+}
+
+// RegisterType registers a factory for documents whose discriminator field
+// (see WithTypeField) equals discriminator. The factory must return a
+// pointer to decode into, e.g. func() interface{} { return &UserCreated{} }.
+func (c *Client) RegisterType(discriminator string, factory func() interface{}) {
+	c.typesMu.Lock()
+	defer c.typesMu.Unlock()
+
+	if c.types == nil {
+		c.types = make(map[string]func() interface{})
+	}
+	c.types[discriminator] = factory
+}
+
+// GetTyped retrieves the value for the given key and decodes it into the
+// type registered for its discriminator field, returning the pointer built
+// by the factory. A missing key returns nil.
+func (c *Client) GetTyped(key string) (interface{}, error) {
+	value, err := c.Get(key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, &DecodeError{Key: key, Err: fmt.Errorf("unexpected value type: %T", value)}
+	}
+	discriminator, ok := doc[c.typeField].(string)
+	if !ok {
+		return nil, &DecodeError{Key: key, Err: fmt.Errorf("missing %q discriminator field", c.typeField)}
+	}
+
+	c.typesMu.RLock()
+	factory := c.types[discriminator]
+	c.typesMu.RUnlock()
+	if factory == nil {
+		return nil, &DecodeError{Key: key, Err: fmt.Errorf("%w: %q", ErrUnknownType, discriminator)}
+	}
+
+	dest := factory()
+	if err := decodeValue(key, value, dest); err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
+
+// decodeValue converts a decoded JSON value into dest
+func decodeValue(key string, value interface{}, dest interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return &DecodeError{Key: key, Type: reflect.TypeOf(dest), Err: err}
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return &DecodeError{Key: key, Type: reflect.TypeOf(dest), Err: err}
+	}
+	return nil
+}
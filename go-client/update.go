@@ -0,0 +1,73 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooManyRetries is returned by Update when every attempt conflicted
+// with a concurrent write
+var ErrTooManyRetries = errors.New("too many retries")
+
+// DefaultUpdateAttempts is the number of attempts made by Update by default
+const DefaultUpdateAttempts = 10
+
+// UpdateOption configures a single Update call
+type UpdateOption func(*updateOptions)
+
+type updateOptions struct {
+	attempts int
+}
+
+// WithUpdateAttempts sets how many times Update tries the read-modify-write
+// cycle before giving up with ErrTooManyRetries
+func WithUpdateAttempts(attempts int) UpdateOption {
+	return func(o *updateOptions) {
+		o.attempts = attempts
+	}
+}
+
+// Update applies mutate to the value at the given key with optimistic
+// concurrency: it reads the value and its version, calls mutate and writes
+// the result only if the version did not change in the meantime, retrying
+// on conflict. mutate receives nil if the key does not exist, and may be
+// called several times. An error from mutate aborts the update and is
+// returned as is.
+func (c *Client) Update(key string, mutate func(current interface{}) (interface{}, error), opts ...UpdateOption) error {
+	o := updateOptions{attempts: DefaultUpdateAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	for attempt := 0; attempt < o.attempts; attempt++ {
+		current, version, found, err := c.GetVersioned(key)
+		if err != nil {
+			return err
+		}
+
+		value, err := mutate(current)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			// Create the key, unless another writer did first
+			err := c.Set(key, value, OnlyIfAbsent())
+			if !errors.Is(err, ErrConditionNotMet) {
+				return err
+			}
+			continue
+		}
+
+		swapped, err := c.CompareAndSwapVersion(key, version, value)
+		if err != nil {
+			return err
+		}
+		if swapped {
+			return nil
+		}
+	}
+
+	cmd := CompareAndSwapVersionCommand{CompareAndSwapVersion: CompareAndSwapVersionData{Key: c.key(key)}}
+	return wrapOperationError(cmd, fmt.Errorf("%w: %d attempts", ErrTooManyRetries, o.attempts))
+}